@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/emersion/go-vcard"
@@ -13,16 +15,79 @@ import (
 
 	"git.luzifer.io/luzifer/birthday-notifier/pkg/config"
 	"git.luzifer.io/luzifer/birthday-notifier/pkg/dateutil"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/dedup"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/report"
 )
 
 type (
 	birthdayEntry struct {
-		contact  vcard.Card
-		birthday time.Time
+		account     string
+		addressBook string
+		contact     vcard.Card
+		birthday    dateutil.Birthday
+		overrides   dateutil.Overrides
 	}
 )
 
-func fetchBirthdays(webdavConfig config.WebdavConfig) (birthdays []birthdayEntry, err error) {
+// accountsByInterval groups the indexes of the accounts by their fetch
+// interval
+func accountsByInterval(accounts []config.WebdavConfig) map[time.Duration][]int {
+	groups := make(map[time.Duration][]int)
+	for i, account := range accounts {
+		groups[account.FetchInterval] = append(groups[account.FetchInterval], i)
+	}
+
+	return groups
+}
+
+// allAccounts returns the indexes of all accounts
+func allAccounts(accounts []config.WebdavConfig) []int {
+	idxs := make([]int, len(accounts))
+	for i := range accounts {
+		idxs[i] = i
+	}
+
+	return idxs
+}
+
+// selectAccounts returns the accounts having the given indexes
+func selectAccounts(accounts []config.WebdavConfig, idxs []int) []config.WebdavConfig {
+	selected := make([]config.WebdavConfig, 0, len(idxs))
+	for _, idx := range idxs {
+		selected = append(selected, accounts[idx])
+	}
+
+	return selected
+}
+
+// fetchAccounts retrieves the contacts of the given accounts
+// concurrently. The result has the same indexes as the accounts, the
+// contacts of failed accounts are nil.
+func fetchAccounts(accounts []config.WebdavConfig) ([][]report.Contact, error) {
+	var (
+		contacts = make([][]report.Contact, len(accounts))
+		errs     = make([]error, len(accounts))
+		wg       sync.WaitGroup
+	)
+
+	for i := range accounts {
+		wg.Go(func() {
+			c, err := fetchContacts(accounts[i])
+			if err != nil {
+				errs[i] = fmt.Errorf("account %q: %w", accounts[i].Label, err)
+				return
+			}
+			contacts[i] = append([]report.Contact{}, c...)
+		})
+	}
+
+	wg.Wait()
+	return contacts, errors.Join(errs...)
+}
+
+// fetchContacts retrieves all contacts from all address books of the
+// configured principal
+func fetchContacts(webdavConfig config.WebdavConfig) (contacts []report.Contact, err error) {
 	client, err := carddav.NewClient(
 		webdav.HTTPClientWithBasicAuth(http.DefaultClient, webdavConfig.User, webdavConfig.Pass),
 		webdavConfig.BaseURL,
@@ -45,30 +110,71 @@ func fetchBirthdays(webdavConfig config.WebdavConfig) (birthdays []birthdayEntry
 	}
 
 	for _, book := range books {
-		contacts, err := client.QueryAddressBook(context.Background(), book.Path, &carddav.AddressBookQuery{})
+		bookName := book.Name
+		if bookName == "" {
+			bookName = book.Path
+		}
+
+		addresses, err := client.QueryAddressBook(context.Background(), book.Path, &carddav.AddressBookQuery{})
 		if err != nil {
 			return nil, fmt.Errorf("getting contacts: %w", err)
 		}
 
-		for _, address := range contacts {
-			bday := address.Card.Get(vcard.FieldBirthday)
-			if bday == nil {
-				continue
-			}
+		for _, address := range addresses {
+			contacts = append(contacts, report.Contact{Account: webdavConfig.Label, AddressBook: bookName, Card: address.Card})
+		}
+	}
 
-			bdayDate, err := dateutil.Parse(bday)
-			if err != nil {
-				logrus.WithField("date", bday).WithError(err).Error("parsing birthday")
-				continue
-			}
+	return contacts, nil
+}
+
+// parseBirthdays extracts the birthdays from the contacts, skips
+// contacts without or with invalid birthdays and reduces duplicates
+// using the deduplicator
+func parseBirthdays(contacts []report.Contact, deduplicator *dedup.Deduplicator) (birthdays []birthdayEntry) {
+	var entries []dedup.Entry
 
-			birthdays = append(birthdays, birthdayEntry{
-				contact:  address.Card,
-				birthday: bdayDate,
-			})
+	for _, c := range contacts {
+		bday := c.Card.Get(vcard.FieldBirthday)
+		if bday == nil {
+			continue
 		}
+
+		bdayDate, err := dateutil.Parse(bday)
+		switch {
+		case errors.Is(err, dateutil.ErrNotSchedulable):
+			logrus.
+				WithError(err).
+				WithField("name", c.Card.PreferredValue(vcard.FieldFormattedName)).
+				Warn("skipping birthday")
+			continue
+
+		case err != nil:
+			logrus.WithField("date", bday).WithError(err).Error("parsing birthday")
+			continue
+		}
+
+		entries = append(entries, dedup.Entry{Account: c.Account, AddressBook: c.AddressBook, Birthday: bdayDate, Card: c.Card})
+	}
+
+	for _, e := range deduplicator.Deduplicate(entries) {
+		overrides, err := dateutil.ParseOverrides(e.Card)
+		if err != nil {
+			logrus.
+				WithError(err).
+				WithField("name", e.Card.PreferredValue(vcard.FieldFormattedName)).
+				Warn("ignoring invalid notification overrides")
+		}
+
+		birthdays = append(birthdays, birthdayEntry{
+			account:     e.Account,
+			addressBook: e.AddressBook,
+			contact:     e.Card,
+			birthday:    e.Birthday,
+			overrides:   overrides,
+		})
 	}
 
 	logrus.Infof("fetched %d birthdays from contacts", len(birthdays))
-	return birthdays, nil
+	return birthdays
 }
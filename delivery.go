@@ -0,0 +1,105 @@
+package main
+
+import (
+	"slices"
+
+	"github.com/sirupsen/logrus"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/actions"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/config"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/dateutil"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/greeting"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/milestone"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/notifier"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/photo"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/scheduler"
+)
+
+// deliverScheduled creates the function executing the deliveries of
+// the scheduler: As deliveries might have been persisted before a
+// restart, the contact and the notifier are looked up again and the
+// delivery is skipped if they vanished, the contact has been muted or
+// the birthday has been acknowledged in the meantime
+func deliverScheduled(
+	configFile config.File,
+	milestones *milestone.Milestones,
+	greeter *greeting.Greeter,
+	photos *photo.Loader,
+) func(*actions.Manager, scheduler.Delivery) {
+	return func(actionManager *actions.Manager, d scheduler.Delivery) {
+		logger := logrus.WithFields(logrus.Fields{
+			"contact":  d.Contact,
+			"kind":     d.Kind,
+			"notifier": d.Notifier,
+		})
+
+		b, ok := findBirthday(d.Contact)
+		switch {
+		case !ok:
+			logger.Warn("contact of scheduled delivery not found")
+			return
+
+		case b.overrides.Mute:
+			logger.Debug("contact has been muted, skipping")
+			return
+		}
+
+		switch d.Kind {
+		case scheduler.KindGreeting:
+			// Greetings delayed by a longer downtime are not sent after
+			// the birthday anymore
+			if greeter == nil || !d.Occurrence.Equal(dateutil.TodayStartOfDay()) || !greeter.OptedIn(b.contact) {
+				logger.Info("greeting is no longer due, skipping")
+				return
+			}
+
+			evt := notifier.Event{Account: b.account, Birthday: b.birthday, Contact: b.contact, Date: d.Occurrence, Locale: configFile.Locale}
+			if err := greeter.Greet(evt); err != nil {
+				logger.WithError(err).Error("sending greeting")
+			}
+
+		case scheduler.KindNotification:
+			idx := slices.IndexFunc(configFile.Notifiers, func(n config.NotifierConfig) bool { return n.Name == d.Notifier })
+			if idx < 0 || !configFile.Notifiers[idx].IsEnabled() {
+				logger.Warn("notifier of scheduled notification not found or disabled")
+				return
+			}
+
+			if actionManager.IsAcknowledged(d.Contact, d.Occurrence) {
+				logger.Debug("notification acknowledged, skipping")
+				return
+			}
+
+			notifierCfg := configFile.Notifiers[idx]
+			evt := notifierEvent(notifierCfg, actionManager, notifier.Event{
+				Account:   b.account,
+				Birthday:  b.birthday,
+				Contact:   b.contact,
+				Date:      d.Occurrence,
+				Milestone: milestones.Reached(b.birthday, d.Occurrence),
+				Photo:     loadPhoto(photos, b.contact),
+			})
+
+			if err := getNotifierByName(notifierCfg.Type).SendNotification(notifierCfg.Settings, evt); err != nil {
+				logger.WithError(err).Error("sending notification")
+			}
+
+		default:
+			logger.Error("unknown kind of scheduled delivery")
+		}
+	}
+}
+
+// findBirthday returns the birthday of the contact having the given
+// contact key (see actions.ContactKey)
+func findBirthday(contactKey string) (birthdayEntry, bool) {
+	birthdaysLock.Lock()
+	defer birthdaysLock.Unlock()
+
+	idx := slices.IndexFunc(birthdays, func(b birthdayEntry) bool { return actions.ContactKey(b.contact) == contactKey })
+	if idx < 0 {
+		return birthdayEntry{}, false
+	}
+
+	return birthdays[idx], true
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/actions"
+)
+
+const httpReadHeaderTimeout = 5 * time.Second
+
+// listenHTTP serves the HTTP API on the given address and blocks until
+// the server fails. The report is only served if a token to protect it
+// is given, the callbacks of the actions are only served if actions are
+// enabled.
+func listenHTTP(addr, reportToken string, actionManager *actions.Manager) error {
+	mux := http.NewServeMux()
+
+	if reportToken != "" {
+		mux.Handle("GET /report", requireToken(reportToken, http.HandlerFunc(handleReport)))
+	}
+
+	if actionManager != nil {
+		mux.Handle("GET /action/{action}", actionManager)
+		mux.Handle("POST /action/{action}", actionManager)
+	}
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: httpReadHeaderTimeout,
+	}
+
+	logrus.WithField("addr", addr).Info("HTTP API listening")
+	if err := srv.ListenAndServe(); err != nil {
+		return fmt.Errorf("listening for HTTP: %w", err)
+	}
+
+	return nil
+}
+
+// handleReport responds with the data-quality report generated on the
+// last fetch of the contacts
+func handleReport(w http.ResponseWriter, _ *http.Request) {
+	birthdaysLock.Lock()
+	r := dataReport
+	birthdaysLock.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(r); err != nil {
+		logrus.WithError(err).Error("encoding report")
+	}
+}
+
+// requireToken only passes requests to the next handler having the
+// given token set as bearer token in the Authorization header
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
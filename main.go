@@ -3,31 +3,60 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"slices"
 	"sync"
+	"text/tabwriter"
 	"time"
 
-	"github.com/Luzifer/go_helpers/fieldcollection"
 	"github.com/Luzifer/rconfig/v2"
 	"github.com/emersion/go-vcard"
 	"github.com/robfig/cron/v3"
 	"github.com/sirupsen/logrus"
 
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/actions"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/advance"
 	"git.luzifer.io/luzifer/birthday-notifier/pkg/config"
 	"git.luzifer.io/luzifer/birthday-notifier/pkg/dateutil"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/dedup"
 	"git.luzifer.io/luzifer/birthday-notifier/pkg/formatter"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/greeting"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/i18n"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/milestone"
 	"git.luzifer.io/luzifer/birthday-notifier/pkg/notifier"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/photo"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/report"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/routing"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/scheduler"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/secrets"
+)
+
+const tablePadding = 2
+
+// Keys in the templates of the notifiers additionally to the event
+// types
+const (
+	templateKeyDefault   = "default"
+	templateKeyMilestone = "milestone"
 )
 
 var (
 	cfg = struct {
 		Config         string `flag:"config,c" default:"config.yaml" description:"Configuration file path"`
+		Listen         string `flag:"listen" default:"" description:"Address to listen on for the HTTP API (disabled if empty)"`
 		LogLevel       string `flag:"log-level" default:"info" description:"Log level (debug, info, warn, error, fatal)"`
+		ReportToken    string `flag:"report-token" default:"" description:"Bearer token required to fetch the report from the HTTP API (report disabled if empty)"`
 		VersionAndExit bool   `flag:"version" default:"false" description:"Prints current version and exits"`
 	}{}
 
-	birthdays     []birthdayEntry
-	birthdaysLock sync.Mutex
+	// accountContacts caches the contacts of each account (same indexes
+	// as the accounts in the configuration file) as they are fetched
+	// in different intervals
+	accountContacts [][]report.Contact
+	birthdays       []birthdayEntry
+	birthdaysLock   sync.Mutex
+	dataReport      report.Report
 
 	version = "dev"
 )
@@ -44,6 +73,11 @@ func initApp() error {
 	}
 	logrus.SetLevel(l)
 
+	// Secrets from the configuration must not end up in the logs,
+	// neither directly nor within error messages
+	logrus.SetFormatter(secrets.RedactingFormatter{Formatter: logrus.StandardLogger().Formatter})
+	secrets.Register(cfg.ReportToken)
+
 	return nil
 }
 
@@ -58,35 +92,140 @@ func main() {
 		os.Exit(0)
 	}
 
+	if args := subcommandArgs(rconfig.Args()); len(args) > 0 {
+		if err = runSubcommand(args); err != nil {
+			logrus.WithError(err).Fatal("running command")
+		}
+		os.Exit(0)
+	}
+
 	configFile, err := config.LoadFromFile(cfg.Config)
 	if err != nil {
 		logrus.WithError(err).Fatal("loading configuration file")
 	}
 
+	if err = formatter.SetTemplateDir(configFile.TemplateDir); err != nil {
+		logrus.WithError(err).Fatal("loading templates")
+	}
+	logrus.WithField("templates", formatter.TemplateNames()).Debug("loaded named templates")
+
 	if err = validateNotifierConfigs(configFile); err != nil {
 		logrus.WithError(err).Fatal("validating configuration")
 	}
 
+	restrictions, err := getNotifierRestrictions(configFile)
+	if err != nil {
+		logrus.WithError(err).Fatal("validating configuration")
+	}
+
 	if err = formatter.SetTemplate(configFile.Template); err != nil {
 		logrus.WithError(err).Fatal("setting template")
 	}
 
-	if birthdays, err = fetchBirthdays(configFile.Webdav); err != nil {
+	formatter.SetAppendNotes(configFile.AppendNotes)
+
+	var milestones *milestone.Milestones
+	if mc := configFile.Milestones; mc != nil {
+		if milestones, err = milestone.New(mc.Ages, mc.Every, mc.NotifyDaysInAdvance); err != nil {
+			logrus.WithError(err).Fatal("validating milestones")
+		}
+
+		if err = formatter.SetMilestones(milestones, mc.Template); err != nil {
+			logrus.WithError(err).Fatal("setting milestone template")
+		}
+	}
+
+	if err = formatter.ValidateTemplates(); err != nil {
+		logrus.WithError(err).Fatal("validating templates")
+	}
+
+	router, err := routing.New(configFile)
+	if err != nil {
+		logrus.WithError(err).Fatal("validating routes")
+	}
+
+	var greeter *greeting.Greeter
+	if configFile.Greeting != nil {
+		if greeter, err = greeting.New(*configFile.Greeting); err != nil {
+			logrus.WithError(err).Fatal("configuring greeting")
+		}
+	}
+
+	var photos *photo.Loader
+	if configFile.Photos != nil {
+		if photos, err = photo.New(*configFile.Photos); err != nil {
+			logrus.WithError(err).Fatal("configuring photos")
+		}
+	}
+
+	var actionManager *actions.Manager
+
+	deliver := deliverScheduled(configFile, milestones, greeter, photos)
+	sched, err := scheduler.New(configFile.StateFile, func(d scheduler.Delivery) { deliver(actionManager, d) })
+	if err != nil {
+		logrus.WithError(err).Fatal("configuring scheduler")
+	}
+
+	if configFile.StateFile == "" && (greeter != nil || hasRestrictions(configFile)) {
+		logrus.Warn("no stateFile configured, deferred notifications and greetings are lost on restart")
+	}
+
+	redeliver := redeliverSnoozed(configFile, sched, restrictions)
+
+	if configFile.Actions != nil {
+		if cfg.Listen == "" {
+			logrus.Fatal("actions require the HTTP API to be enabled (--listen)")
+		}
+
+		if actionManager, err = actions.New(*configFile.Actions, func(s actions.Snooze) { redeliver(actionManager, s) }); err != nil {
+			logrus.WithError(err).Fatal("configuring actions")
+		}
+	}
+
+	deduplicator, err := dedup.New(configFile.Deduplicate)
+	if err != nil {
+		logrus.WithError(err).Fatal("configuring deduplication")
+	}
+
+	accountContacts = make([][]report.Contact, len(configFile.Accounts))
+	if err = updateBirthdays(configFile.Accounts, allAccounts(configFile.Accounts), deduplicator); err != nil {
 		logrus.WithError(err).Fatal("initially fetching birthdays")
 	}
 
+	// Snoozes and deferred deliveries survive restarts: Schedule the
+	// ones not yet delivered again
+	for _, s := range actionManager.PendingSnoozes() {
+		redeliver(actionManager, s)
+	}
+	sched.Resume()
+
+	if cfg.Listen != "" {
+		go func() {
+			if err := listenHTTP(cfg.Listen, cfg.ReportToken, actionManager); err != nil {
+				logrus.WithError(err).Fatal("running HTTP server")
+			}
+		}()
+	}
+
 	crontab := cron.New()
 
-	// Periodically update birthdays
-	if _, err = crontab.AddFunc(
-		fmt.Sprintf("@every %s", configFile.Webdav.FetchInterval),
-		cronFetchBirthdays(configFile.Webdav),
-	); err != nil {
-		logrus.WithError(err).Fatal("adding update-cron")
+	// Periodically update birthdays, accounts sharing the same interval
+	// are fetched together
+	for interval, idxs := range accountsByInterval(configFile.Accounts) {
+		if _, err = crontab.AddFunc(
+			fmt.Sprintf("@every %s", interval),
+			func() {
+				if err := updateBirthdays(configFile.Accounts, idxs, deduplicator); err != nil {
+					logrus.WithError(err).Error("updating birthdays")
+				}
+			},
+		); err != nil {
+			logrus.WithError(err).Fatal("adding update-cron")
+		}
 	}
 
 	// Send notifications at midnight
-	if _, err = crontab.AddFunc("@midnight", cronSendNotifications(configFile)); err != nil {
+	if _, err = crontab.AddFunc("@midnight", cronSendNotifications(configFile, router, milestones, greeter, actionManager, sched, restrictions)); err != nil {
 		logrus.WithError(err).Fatal("adding update-cron")
 	}
 
@@ -101,66 +240,316 @@ func main() {
 	}
 }
 
-func cronFetchBirthdays(webdavConfig config.WebdavConfig) func() {
+//revive:disable-next-line:argument-limit // Dependencies of the cron-job
+func cronSendNotifications(
+	configFile config.File,
+	router *routing.Router,
+	milestones *milestone.Milestones,
+	greeter *greeting.Greeter,
+	actionManager *actions.Manager,
+	sched *scheduler.Scheduler,
+	restrictions []scheduler.Restrictions,
+) func() {
 	return func() {
+		// Work on a copy to not block fetching contacts and the HTTP API
+		// while the deliveries are scheduled
 		birthdaysLock.Lock()
-		defer birthdaysLock.Unlock()
+		entries := slices.Clone(birthdays)
+		birthdaysLock.Unlock()
+
+		today := dateutil.TodayStartOfDay()
+
+		for _, b := range entries {
+			if b.overrides.Mute {
+				continue
+			}
 
+			contactKey := actions.ContactKey(b.contact)
+
+			if greeter != nil && b.birthday.IsToday() && greeter.OptedIn(b.contact) {
+				sched.Schedule(scheduler.Delivery{
+					At:         greeter.SendTime(today),
+					Contact:    contactKey,
+					Kind:       scheduler.KindGreeting,
+					Occurrence: today,
+				})
+			}
+
+			target := resolveTarget(router, b)
+
+			for _, occurrence := range notificationsDue(b.birthday, target.Advance, milestones) {
+				if actionManager.IsAcknowledged(contactKey, occurrence) {
+					continue
+				}
+
+				for _, i := range target.Notifiers {
+					scheduleNotification(sched, restrictions[i], configFile.Notifiers[i].Name, contactKey, occurrence)
+				}
+
+				scheduleEscalation(configFile, actionManager, restrictions, target, b.contact, occurrence)
+			}
+		}
+	}
+}
+
+// hasRestrictions reports whether any notifier has quiet hours or
+// blackout dates configured and therefore defers notifications
+func hasRestrictions(configFile config.File) bool {
+	return slices.ContainsFunc(configFile.Notifiers, func(n config.NotifierConfig) bool {
+		return n.QuietHours != nil || len(n.BlackoutDates) > 0
+	})
+}
+
+// getNotifierRestrictions parses the quiet hours and blackout dates
+// of all notifiers into a slice having the same indexes as the
+// notifiers in the configuration file
+func getNotifierRestrictions(configFile config.File) ([]scheduler.Restrictions, error) {
+	restrictions := make([]scheduler.Restrictions, len(configFile.Notifiers))
+
+	for i, notifierCfg := range configFile.Notifiers {
 		var err error
-		if birthdays, err = fetchBirthdays(webdavConfig); err != nil {
-			logrus.WithError(err).Error("updating birthdays")
+		if restrictions[i], err = scheduler.NewRestrictions(notifierCfg.QuietHours, notifierCfg.BlackoutDates); err != nil {
+			return nil, fmt.Errorf("notifier %q: %w", notifierCfg.Name, err)
 		}
 	}
+
+	return restrictions, nil
+}
+
+// loadPhoto returns the photo of the contact or nil if it has none or
+// the photo cannot be loaded: Notifications are sent without photo
+// instead of failing
+func loadPhoto(photos *photo.Loader, contact vcard.Card) *photo.Photo {
+	p, err := photos.Get(contact)
+	if err != nil {
+		logrus.
+			WithError(err).
+			WithField("name", contact.PreferredValue(vcard.FieldFormattedName)).
+			Warn("loading photo")
+		return nil
+	}
+
+	return p
 }
 
-func cronSendNotifications(configFile config.File) func() {
-	return func() {
-		birthdaysLock.Lock()
-		defer birthdaysLock.Unlock()
+// notificationsDue evaluates the advance rules (plus the notification
+// on the day itself and the milestone rules for milestone birthdays)
+// against the next and the previous occurrence of the birthday and
+// returns the occurrences to notify about today
+func notificationsDue(birthday dateutil.Birthday, rules []advance.Rule, milestones *milestone.Milestones) (occurrences []time.Time) {
+	today := dateutil.TodayStartOfDay()
 
-		for _, b := range birthdays {
-			for _, advanceDays := range append(configFile.NotifyDaysInAdvance, 0) {
-				if !dateutil.IsToday(notifyDate(dateutil.ProjectToNextBirthday(b.birthday), advanceDays)) {
-					continue
-				}
+	for _, occurrence := range []time.Time{
+		birthday.NextOccurrence(),
+		birthday.PreviousOccurrence(),
+	} {
+		occurrenceRules := append(slices.Clone(rules), advance.OnTheDay)
+		occurrenceRules = append(occurrenceRules, milestones.Advance(birthday, occurrence)...)
 
-				for i := range configFile.Notifiers {
-					notifyInstance := getNotifierByName(configFile.Notifiers[i].Type)
-
-					go func(
-						n notifier.Notifier,
-						settings *fieldcollection.FieldCollection,
-						contact vcard.Card,
-						when time.Time,
-					) {
-						if err := n.SendNotification(settings, contact, when); err != nil {
-							logrus.
-								WithError(err).
-								WithField("name", contact.Get(vcard.FieldFormattedName).Value).
-								Error("sending notification")
-						}
-					}(notifyInstance, configFile.Notifiers[i].Settings, b.contact, b.birthday)
-				}
+		for _, rule := range occurrenceRules {
+			if notifyAt, ok := rule.NotifyDate(occurrence); ok && notifyAt.Equal(today) {
+				// Multiple rules might match the same day, only notify once
+				occurrences = append(occurrences, occurrence)
+				break
 			}
 		}
 	}
+
+	return occurrences
+}
+
+// scheduleNotification schedules the notification through the
+// notifier to be sent immediately or deferred to the end of the quiet
+// hours / blackout dates of the notifier
+func scheduleNotification(
+	sched *scheduler.Scheduler,
+	restrictions scheduler.Restrictions,
+	notifierName, contactKey string,
+	occurrence time.Time,
+) {
+	at := restrictions.NextAllowed(time.Now())
+	if at.After(time.Now()) {
+		logrus.WithFields(logrus.Fields{
+			"contact":  contactKey,
+			"notifier": notifierName,
+			"until":    at,
+		}).Info("deferring notification")
+	}
+
+	sched.Schedule(scheduler.Delivery{
+		At:         at,
+		Contact:    contactKey,
+		Kind:       scheduler.KindNotification,
+		Notifier:   notifierName,
+		Occurrence: occurrence,
+	})
+}
+
+// notifierTemplate selects the name of the template configured for the
+// event in the notifier: The milestone template for milestones, the
+// template for the type of the event or the default template. An
+// empty name selects the global template.
+func notifierTemplate(notifierCfg config.NotifierConfig, evt notifier.Event) string {
+	if name, ok := notifierCfg.Templates[templateKeyMilestone]; ok && evt.Milestone {
+		return name
+	}
+
+	if name, ok := notifierCfg.Templates[evt.Type()]; ok {
+		return name
+	}
+
+	return notifierCfg.Templates[templateKeyDefault]
+}
+
+// resolveTarget determines the notifiers and advance rules for the
+// birthday by routing the contact and applying the overrides given in
+// the contact itself. Overridden notifiers are removed from the
+// escalation of the route.
+func resolveTarget(router *routing.Router, b birthdayEntry) routing.Target {
+	target := router.Resolve(b.contact, b.account, b.addressBook)
+
+	if b.overrides.Advance != nil {
+		target.Advance = b.overrides.Advance
+	}
+
+	if b.overrides.Notifiers != nil {
+		notifiers, err := router.ResolveNotifiers(b.overrides.Notifiers)
+		if err != nil {
+			logrus.
+				WithError(err).
+				WithField("name", b.contact.PreferredValue(vcard.FieldFormattedName)).
+				Warn("ignoring invalid notifiers override")
+			return target
+		}
+		target = target.WithNotifiers(notifiers)
+	}
+
+	return target
 }
 
-func notifyDate(t time.Time, daysInAdvance int) time.Time {
-	return time.Date(t.Year(), t.Month(), t.Day()-daysInAdvance, 0, 0, 0, 0, time.Local)
+// subcommandArgs strips the binary name from the positional arguments
+// and returns the subcommand with its arguments (empty if none given)
+func subcommandArgs(args []string) []string {
+	if len(args) == 0 {
+		return nil
+	}
+
+	return args[1:]
+}
+
+// runSubcommand executes the subcommand given as positional argument
+// instead of starting the notifier
+func runSubcommand(args []string) error {
+	switch args[0] {
+	case "report":
+		configFile, err := config.LoadFromFile(cfg.Config)
+		if err != nil {
+			return fmt.Errorf("loading configuration file: %w", err)
+		}
+
+		accountContacts, err := fetchAccounts(configFile.Accounts)
+		if err != nil {
+			return fmt.Errorf("fetching contacts: %w", err)
+		}
+
+		return report.Generate(slices.Concat(accountContacts...), time.Now()).WriteText(os.Stdout)
+
+	case "template-funcs":
+		return printTemplateFuncs(os.Stdout)
+
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// printTemplateFuncs lists the functions available in the templates
+func printTemplateFuncs(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, tablePadding, ' ', 0)
+	for _, f := range formatter.TemplateFuncs() {
+		fmt.Fprintf(tw, "%s\t%s\n", f.Usage, f.Description)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("writing function list: %w", err)
+	}
+
+	return nil
+}
+
+// updateBirthdays fetches the contacts of the accounts having the given
+// indexes, replaces the known birthdays with the ones of all accounts
+// and updates the data-quality report
+func updateBirthdays(accounts []config.WebdavConfig, idxs []int, deduplicator *dedup.Deduplicator) error {
+	fetched, err := fetchAccounts(selectAccounts(accounts, idxs))
+
+	birthdaysLock.Lock()
+	defer birthdaysLock.Unlock()
+
+	// Keep the contacts of failed accounts from their last fetch
+	for i, idx := range idxs {
+		if fetched[i] != nil {
+			accountContacts[idx] = fetched[i]
+		}
+	}
+
+	contacts := slices.Concat(accountContacts...)
+	birthdays, dataReport = parseBirthdays(contacts, deduplicator), report.Generate(contacts, time.Now())
+	if len(dataReport.Issues) > 0 {
+		logrus.WithField("issues", len(dataReport.Issues)).Warn("contacts have data-quality issues, see report")
+	}
+
+	if err != nil {
+		return fmt.Errorf("fetching contacts: %w", err)
+	}
+
+	return nil
 }
 
 func validateNotifierConfigs(configFile config.File) (err error) {
+	seenNames := make(map[string]bool)
+
+	if _, err = i18n.Get(configFile.Locale); err != nil {
+		return fmt.Errorf("validating locale: %w", err)
+	}
+
 	for i := range configFile.Notifiers {
 		notifierCfg := configFile.Notifiers[i]
 
+		if seenNames[notifierCfg.Name] {
+			return fmt.Errorf("notifier name %q is used more than once", notifierCfg.Name)
+		}
+		seenNames[notifierCfg.Name] = true
+
 		n := getNotifierByName(notifierCfg.Type)
 		if n == nil {
-			return fmt.Errorf("notifier %q does not exist", notifierCfg.Type)
+			return fmt.Errorf("notifier %q: type %q does not exist", notifierCfg.Name, notifierCfg.Type)
+		}
+
+		if !notifierCfg.IsEnabled() {
+			logrus.WithField("notifier", notifierCfg.Name).Info("notifier is disabled")
+			continue
+		}
+
+		if _, err = i18n.Get(notifierCfg.Locale); err != nil {
+			return fmt.Errorf("notifier %q: validating locale: %w", notifierCfg.Name, err)
+		}
+
+		for key, name := range notifierCfg.Templates {
+			switch key {
+			case notifier.EventTypeAdvance, notifier.EventTypeAfter, notifier.EventTypeToday, templateKeyDefault, templateKeyMilestone:
+				// Fine
+
+			default:
+				return fmt.Errorf("notifier %q: unknown template event type %q", notifierCfg.Name, key)
+			}
+
+			if err = formatter.ValidateNamedTemplate(name, key == templateKeyMilestone); err != nil {
+				return fmt.Errorf("notifier %q: validating %s template: %w", notifierCfg.Name, key, err)
+			}
 		}
 
 		if err = n.ValidateSettings(notifierCfg.Settings); err != nil {
-			return fmt.Errorf("settings for %q are invalid: %w", notifierCfg.Type, err)
+			return fmt.Errorf("notifier %q: settings for %q are invalid: %w", notifierCfg.Name, notifierCfg.Type, err)
 		}
 	}
 
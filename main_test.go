@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-vcard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/config"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/dateutil"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/routing"
+)
+
+func TestSubcommandArgs(t *testing.T) {
+	assert.Empty(t, subcommandArgs(nil))
+	assert.Empty(t, subcommandArgs([]string{"birthday-notifier"}))
+	assert.Equal(t, []string{"report"}, subcommandArgs([]string{"birthday-notifier", "report"}))
+	assert.Equal(t, []string{"report", "extra"}, subcommandArgs([]string{"/usr/bin/birthday-notifier", "report", "extra"}))
+}
+
+func TestRunSubcommandUnknown(t *testing.T) {
+	assert.ErrorContains(t, runSubcommand([]string{"foo"}), `unknown command "foo"`)
+}
+
+func TestResolveTargetNotifiersOverride(t *testing.T) {
+	router, err := routing.New(config.File{
+		Actions: &config.ActionsConfig{},
+		Notifiers: []config.NotifierConfig{
+			{Name: "slack", Type: "slack"},
+			{Name: "pushover-urgent", Type: "pushover"},
+			{Name: "email", Type: "exec"},
+		},
+		Routes: []config.RouteConfig{{
+			Escalation: []config.EscalationStepConfig{
+				{After: 4 * time.Hour, Notifiers: []string{"pushover-urgent"}},
+				{After: 8 * time.Hour, Notifiers: []string{"email"}},
+			},
+			Notifiers: []string{"slack"},
+		}},
+	})
+	require.NoError(t, err)
+
+	b := birthdayEntry{contact: vcard.Card{}}
+	assert.Len(t, resolveTarget(router, b).Escalation, 2)
+
+	// Notifiers of the override must not be escalated to as they share
+	// the follow-up state with the initial notification
+	b.overrides = dateutil.Overrides{Notifiers: []string{"pushover"}}
+	target := resolveTarget(router, b)
+	assert.Equal(t, []int{1}, target.Notifiers)
+	assert.Equal(t, []routing.EscalationStep{{After: 8 * time.Hour, Notifiers: []int{2}}}, target.Escalation)
+}
+
+func TestRequireToken(t *testing.T) {
+	h := requireToken("s3cr3t", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for auth, code := range map[string]int{
+		"":              http.StatusUnauthorized,
+		"s3cr3t":        http.StatusUnauthorized,
+		"Bearer wrong":  http.StatusUnauthorized,
+		"Bearer s3cr3t": http.StatusNoContent,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/report", nil)
+		if auth != "" {
+			r.Header.Set("Authorization", auth)
+		}
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		assert.Equal(t, code, w.Code, auth)
+	}
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"git.luzifer.io/luzifer/birthday-notifier/pkg/notifier"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/notifier/discord"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/notifier/exec"
 	"git.luzifer.io/luzifer/birthday-notifier/pkg/notifier/log"
 	"git.luzifer.io/luzifer/birthday-notifier/pkg/notifier/pushover"
 	"git.luzifer.io/luzifer/birthday-notifier/pkg/notifier/slack"
@@ -9,6 +11,12 @@ import (
 
 func getNotifierByName(name string) notifier.Notifier {
 	switch name {
+	case "discord":
+		return discord.Notifier{}
+
+	case "exec":
+		return exec.Notifier{}
+
 	case "log":
 		return log.Notifier{}
 
@@ -0,0 +1,497 @@
+// Package actions contains the handling of actions the user can take on
+// a notification (acknowledge, snooze) through signed links pointing
+// to the HTTP API including the persistent state of these actions
+package actions
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emersion/go-vcard"
+	"github.com/sirupsen/logrus"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/config"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/notifier"
+)
+
+// DefaultSnooze is the time a snoozed notification is delivered again
+// after if not configured otherwise
+const DefaultSnooze = 24 * time.Hour
+
+const (
+	linkValidity    = 7 * 24 * time.Hour
+	minSecretLength = 16
+	stateFileMode   = 0o600
+	stateRetention  = 30 * 24 * time.Hour
+)
+
+// confirmPage is shown when opening an action link and after the action
+// was executed. The form posts to the URL of the page itself.
+var confirmPage = template.Must(template.New("confirm").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>birthday-notifier</title>
+</head>
+<body>
+<p>{{ .Message }}</p>
+{{- if .Button }}
+<form method="post"><button type="submit">{{ .Button }}</button></form>
+{{- end }}
+</body>
+</html>
+`))
+
+// Query parameters of the action links
+const (
+	paramContact    = "c"
+	paramExpires    = "e"
+	paramNotifier   = "n"
+	paramOccurrence = "d"
+	paramSignature  = "s"
+)
+
+type (
+	// Manager creates the action links, handles the callbacks and keeps
+	// the state of the actions. A nil Manager has actions disabled.
+	Manager struct {
+		baseURL   *url.URL
+		redeliver func(Snooze)
+		secret    []byte
+		snooze    time.Duration
+		stateFile string
+
+		lock  sync.Mutex
+		state state
+	}
+
+	// Snooze describes a notification to be delivered again through the
+	// given notifier: Either snoozed by the user or as escalation of an
+	// unacknowledged notification
+	Snooze struct {
+		Contact    string
+		Notifier   string
+		Occurrence time.Time
+		Until      time.Time
+	}
+
+	confirmPageData struct {
+		// Button to confirm the action with, empty after the action
+		// was executed
+		Button  string
+		Message string
+	}
+
+	state struct {
+		Events map[string]*eventState `json:"events"`
+	}
+
+	eventState struct {
+		Acknowledged *time.Time           `json:"acknowledged,omitempty"`
+		Contact      string               `json:"contact"`
+		Occurrence   string               `json:"occurrence"`
+		Snoozes      map[string]time.Time `json:"snoozes,omitempty"`
+	}
+)
+
+// New validates the configuration, loads the persisted state and
+// creates a new Manager. The redeliver function is called for every
+// snoozed notification when it is snoozed: It is responsible to deliver
+// the notification again at Snooze.Until.
+func New(cfg config.ActionsConfig, redeliver func(Snooze)) (*Manager, error) {
+	baseURL, err := url.Parse(cfg.BaseURL)
+	if err != nil || (baseURL.Scheme != "http" && baseURL.Scheme != "https") || baseURL.Host == "" {
+		return nil, fmt.Errorf("baseURL must be an absolute http(s) URL")
+	}
+
+	switch {
+	case len(cfg.Secret) < minSecretLength:
+		return nil, fmt.Errorf("secret must have at least %d characters", minSecretLength)
+
+	case cfg.Snooze < 0:
+		return nil, fmt.Errorf("snooze must not be negative")
+
+	case cfg.StateFile == "":
+		return nil, fmt.Errorf("stateFile is mandatory")
+	}
+
+	if cfg.Snooze == 0 {
+		cfg.Snooze = DefaultSnooze
+	}
+
+	m := &Manager{
+		baseURL:   baseURL,
+		redeliver: redeliver,
+		secret:    []byte(cfg.Secret),
+		snooze:    cfg.Snooze,
+		stateFile: cfg.StateFile,
+		state:     state{Events: make(map[string]*eventState)},
+	}
+
+	if err = m.load(); err != nil {
+		return nil, fmt.Errorf("loading state: %w", err)
+	}
+
+	return m, nil
+}
+
+// serveConfirmation renders the page asking to confirm the action
+func (m *Manager) serveConfirmation(w http.ResponseWriter, action string) {
+	var data confirmPageData
+
+	switch action {
+	case notifier.ActionAcknowledge:
+		data.Button = "Acknowledge"
+		data.Message = "Acknowledge the reminder? You will not be notified again about this birthday."
+	case notifier.ActionSnooze:
+		data.Button = "Snooze"
+		data.Message = fmt.Sprintf("Snooze the reminder? You will be notified again in %s.", m.snooze)
+	}
+
+	m.writePage(w, data)
+}
+
+// writePage renders the confirmation page with the given data
+func (*Manager) writePage(w http.ResponseWriter, data confirmPageData) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := confirmPage.Execute(w, data); err != nil {
+		logrus.WithError(err).Debug("writing action page")
+	}
+}
+
+// ContactKey returns the key identifying the contact in the action
+// links and the state: Its UID or its formatted name
+func ContactKey(contact vcard.Card) string {
+	if uid := contact.Value(vcard.FieldUID); uid != "" {
+		return "uid:" + uid
+	}
+
+	return "name:" + contact.PreferredValue(vcard.FieldFormattedName)
+}
+
+// Escalate stores the follow-up notification to be sent if the
+// notification is not acknowledged until Snooze.Until and passes it to
+// the redeliver function
+func (m *Manager) Escalate(s Snooze) error {
+	if m == nil {
+		return nil
+	}
+
+	if err := m.addSnooze(s); err != nil {
+		return err
+	}
+
+	if m.redeliver != nil {
+		m.redeliver(s)
+	}
+
+	return nil
+}
+
+// IsAcknowledged returns whether the occurrence of the birthday of the
+// contact has been acknowledged
+func (m *Manager) IsAcknowledged(contactKey string, occurrence time.Time) bool {
+	if m == nil {
+		return false
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	e, ok := m.state.Events[eventID(contactKey, occurrence)]
+	return ok && e.Acknowledged != nil
+}
+
+// PendingSnoozes returns the snoozed notifications stored in the state
+// to be scheduled again after a restart
+func (m *Manager) PendingSnoozes() (snoozes []Snooze) {
+	if m == nil {
+		return nil
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, e := range m.state.Events {
+		if e.Acknowledged != nil {
+			continue
+		}
+
+		occurrence, err := parseOccurrence(e.Occurrence)
+		if err != nil {
+			continue
+		}
+
+		for notifierName, until := range e.Snoozes {
+			snoozes = append(snoozes, Snooze{Contact: e.Contact, Notifier: notifierName, Occurrence: occurrence, Until: until})
+		}
+	}
+
+	slices.SortFunc(snoozes, func(a, b Snooze) int { return a.Until.Compare(b.Until) })
+	return snoozes
+}
+
+// Take removes the snooze from the state when it is due and reports
+// whether the notification should be delivered: The snooze must not
+// have been replaced by a later one for the same notifier and the
+// birthday must not have been acknowledged.
+func (m *Manager) Take(s Snooze) bool {
+	if m == nil {
+		return false
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	e, ok := m.state.Events[eventID(s.Contact, s.Occurrence)]
+	if !ok || e.Acknowledged != nil {
+		return false
+	}
+
+	if until, ok := e.Snoozes[s.Notifier]; !ok || !until.Equal(s.Until) {
+		return false
+	}
+
+	delete(e.Snoozes, s.Notifier)
+	if err := m.save(); err != nil {
+		logrus.WithError(err).Error("saving action state")
+	}
+
+	return true
+}
+
+// ServeHTTP handles the callbacks of the action links registered as
+// `GET /action/{action}` and `POST /action/{action}`: Opening the link
+// shows a confirmation page (so link scanners and previews don't
+// trigger the action), the action is executed when it is submitted.
+func (m *Manager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	action := r.PathValue("action")
+	q := r.URL.Query()
+
+	if !m.validSignature(action, q) {
+		http.Error(w, "invalid or expired link", http.StatusForbidden)
+		return
+	}
+
+	if !slices.Contains([]string{notifier.ActionAcknowledge, notifier.ActionSnooze}, action) {
+		http.Error(w, "unknown action", http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		m.serveConfirmation(w, action)
+		return
+	}
+
+	var (
+		contactKey   = q.Get(paramContact)
+		notifierName = q.Get(paramNotifier)
+	)
+
+	occurrence, err := parseOccurrence(q.Get(paramOccurrence))
+	if err != nil {
+		http.Error(w, "invalid occurrence", http.StatusBadRequest)
+		return
+	}
+
+	logger := logrus.WithFields(logrus.Fields{
+		"action":     action,
+		"contact":    contactKey,
+		"notifier":   notifierName,
+		"occurrence": q.Get(paramOccurrence),
+	})
+
+	var response string
+	switch action {
+	case notifier.ActionAcknowledge:
+		err = m.acknowledge(contactKey, occurrence)
+		response = "Reminder acknowledged, you will not be notified again about this birthday."
+
+	case notifier.ActionSnooze:
+		s := Snooze{Contact: contactKey, Notifier: notifierName, Occurrence: occurrence, Until: time.Now().Add(m.snooze)}
+		err = m.addSnooze(s)
+		if err == nil && m.redeliver != nil {
+			m.redeliver(s)
+		}
+		response = fmt.Sprintf("Reminder snoozed, you will be notified again at %s.", s.Until.Format("2006-01-02 15:04"))
+	}
+
+	if err != nil {
+		logger.WithError(err).Error("executing action")
+		http.Error(w, "executing action failed", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("executed notification action")
+
+	m.writePage(w, confirmPageData{Message: response})
+}
+
+// URLs returns the signed action links for the notification about the
+// occurrence of the birthday of the contact sent through the notifier
+func (m *Manager) URLs(contactKey string, occurrence time.Time, notifierName string) map[string]string {
+	if m == nil {
+		return nil
+	}
+
+	// Links stay valid for some time after the birthday (or after the
+	// notification for ones sent later than that)
+	expires := max(occurrence.Unix(), time.Now().Unix()) + int64(linkValidity/time.Second)
+
+	urls := make(map[string]string)
+	for _, action := range []string{notifier.ActionAcknowledge, notifier.ActionSnooze} {
+		q := url.Values{
+			paramContact:    {contactKey},
+			paramExpires:    {strconv.FormatInt(expires, 10)},
+			paramNotifier:   {notifierName},
+			paramOccurrence: {occurrence.Format(time.DateOnly)},
+		}
+		q.Set(paramSignature, m.sign(action, q))
+
+		u := m.baseURL.JoinPath("action", action)
+		u.RawQuery = q.Encode()
+		urls[action] = u.String()
+	}
+
+	return urls
+}
+
+func (m *Manager) acknowledge(contactKey string, occurrence time.Time) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	now := time.Now()
+	e := m.getEvent(contactKey, occurrence)
+	e.Acknowledged, e.Snoozes = &now, nil
+
+	return m.save()
+}
+
+func (m *Manager) addSnooze(s Snooze) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	e := m.getEvent(s.Contact, s.Occurrence)
+	if e.Snoozes == nil {
+		e.Snoozes = make(map[string]time.Time)
+	}
+	e.Snoozes[s.Notifier] = s.Until
+
+	return m.save()
+}
+
+// getEvent returns the state of the event creating it if required,
+// the lock MUST be held by the caller
+func (m *Manager) getEvent(contactKey string, occurrence time.Time) *eventState {
+	id := eventID(contactKey, occurrence)
+	if _, ok := m.state.Events[id]; !ok {
+		m.state.Events[id] = &eventState{Contact: contactKey, Occurrence: occurrence.Format(time.DateOnly)}
+	}
+
+	return m.state.Events[id]
+}
+
+func (m *Manager) load() error {
+	f, err := os.Open(m.stateFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("opening state file: %w", err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			logrus.WithError(err).Error("closing state file (leaked fd)")
+		}
+	}()
+
+	if err = json.NewDecoder(f).Decode(&m.state); err != nil {
+		return fmt.Errorf("decoding state file: %w", err)
+	}
+
+	if m.state.Events == nil {
+		m.state.Events = make(map[string]*eventState)
+	}
+
+	return nil
+}
+
+// save removes outdated events from the state and writes it to the
+// state file, the lock MUST be held by the caller
+func (m *Manager) save() error {
+	cutoff := time.Now().Add(-stateRetention)
+	for id, e := range m.state.Events {
+		if occurrence, err := parseOccurrence(e.Occurrence); err != nil || occurrence.Before(cutoff) {
+			delete(m.state.Events, id)
+		}
+	}
+
+	data, err := json.MarshalIndent(m.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding state: %w", err)
+	}
+
+	// Write to a temporary file first to not corrupt the state when
+	// failing in the middle of writing
+	tmp := filepath.Join(filepath.Dir(m.stateFile), "."+filepath.Base(m.stateFile)+".tmp")
+	if err = os.WriteFile(tmp, data, stateFileMode); err != nil {
+		return fmt.Errorf("writing state file: %w", err)
+	}
+
+	if err = os.Rename(tmp, m.stateFile); err != nil {
+		return fmt.Errorf("replacing state file: %w", err)
+	}
+
+	return nil
+}
+
+func (m *Manager) sign(action string, q url.Values) string {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte(strings.Join([]string{
+		action,
+		q.Get(paramContact),
+		q.Get(paramOccurrence),
+		q.Get(paramNotifier),
+		q.Get(paramExpires),
+	}, "\x00")))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (m *Manager) validSignature(action string, q url.Values) bool {
+	expires, err := strconv.ParseInt(q.Get(paramExpires), 10, 64)
+	if err != nil || time.Now().After(time.Unix(expires, 0)) {
+		return false
+	}
+
+	return hmac.Equal([]byte(m.sign(action, q)), []byte(q.Get(paramSignature)))
+}
+
+func eventID(contactKey string, occurrence time.Time) string {
+	return contactKey + "|" + occurrence.Format(time.DateOnly)
+}
+
+func parseOccurrence(v string) (time.Time, error) {
+	t, err := time.ParseInLocation(time.DateOnly, v, time.Local)
+	if err != nil {
+		return t, fmt.Errorf("parsing occurrence: %w", err)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,211 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-vcard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/config"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/dateutil"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/notifier"
+)
+
+const testSecret = "0123456789abcdef"
+
+func newTestManager(t *testing.T, stateFile string, redeliver func(Snooze)) *Manager {
+	t.Helper()
+
+	m, err := New(config.ActionsConfig{
+		BaseURL:   "https://birthdays.example.com/api/",
+		Secret:    testSecret,
+		Snooze:    time.Hour,
+		StateFile: stateFile,
+	}, redeliver)
+	require.NoError(t, err)
+
+	return m
+}
+
+func serve(t *testing.T, m *Manager, method, link string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /action/{action}", m)
+	mux.Handle("POST /action/{action}", m)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(method, "/action/"+filepath.Base(u.Path)+"?"+u.RawQuery, nil))
+
+	return w
+}
+
+func TestAcknowledge(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	m := newTestManager(t, stateFile, nil)
+
+	occurrence := dateutil.TodayStartOfDay()
+	urls := m.URLs("uid:1234", occurrence, "pushover")
+	require.Len(t, urls, 2)
+	assert.Contains(t, urls[notifier.ActionAcknowledge], "https://birthdays.example.com/api/action/acknowledge?")
+
+	assert.False(t, m.IsAcknowledged("uid:1234", occurrence))
+	assert.Equal(t, http.StatusOK, serve(t, m, http.MethodPost, urls[notifier.ActionAcknowledge]).Code)
+	assert.True(t, m.IsAcknowledged("uid:1234", occurrence))
+	assert.False(t, m.IsAcknowledged("uid:1234", occurrence.AddDate(1, 0, 0)))
+	assert.False(t, m.IsAcknowledged("uid:5678", occurrence))
+
+	// State is persisted
+	assert.True(t, newTestManager(t, stateFile, nil).IsAcknowledged("uid:1234", occurrence))
+}
+
+func TestSnooze(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+
+	var redelivered []Snooze
+	m := newTestManager(t, stateFile, func(s Snooze) { redelivered = append(redelivered, s) })
+
+	occurrence := dateutil.TodayStartOfDay()
+	assert.Equal(t, http.StatusOK, serve(t, m, http.MethodPost, m.URLs("uid:1234", occurrence, "pushover")[notifier.ActionSnooze]).Code)
+
+	require.Len(t, redelivered, 1)
+	assert.Equal(t, "uid:1234", redelivered[0].Contact)
+	assert.Equal(t, "pushover", redelivered[0].Notifier)
+	assert.True(t, occurrence.Equal(redelivered[0].Occurrence))
+	assert.WithinDuration(t, time.Now().Add(time.Hour), redelivered[0].Until, time.Minute)
+
+	// Snoozes are restored after a restart until redelivered
+	m = newTestManager(t, stateFile, nil)
+	require.Len(t, m.PendingSnoozes(), 1)
+	assert.True(t, m.PendingSnoozes()[0].Until.Equal(redelivered[0].Until))
+	assert.True(t, m.Take(m.PendingSnoozes()[0]))
+	assert.False(t, m.Take(redelivered[0]), "snooze must only be taken once")
+	assert.Empty(t, m.PendingSnoozes())
+	assert.Empty(t, newTestManager(t, stateFile, nil).PendingSnoozes())
+}
+
+func TestEscalate(t *testing.T) {
+	var scheduled []Snooze
+	m := newTestManager(t, filepath.Join(t.TempDir(), "state.json"), func(s Snooze) { scheduled = append(scheduled, s) })
+
+	occurrence := dateutil.TodayStartOfDay()
+	escalation := Snooze{Contact: "uid:1234", Notifier: "pushover", Occurrence: occurrence, Until: time.Now().Add(time.Hour)}
+	require.NoError(t, m.Escalate(escalation))
+	require.Equal(t, []Snooze{escalation}, scheduled)
+
+	// Snoozing the escalated notification replaces the escalation
+	assert.Equal(t, http.StatusOK, serve(t, m, http.MethodPost, m.URLs("uid:1234", occurrence, "pushover")[notifier.ActionSnooze]).Code)
+	require.Len(t, scheduled, 2)
+	assert.False(t, m.Take(escalation))
+	assert.True(t, m.Take(scheduled[1]))
+
+	// Acknowledged notifications are not escalated
+	require.NoError(t, m.Escalate(escalation))
+	assert.Equal(t, http.StatusOK, serve(t, m, http.MethodPost, m.URLs("uid:1234", occurrence, "slack")[notifier.ActionAcknowledge]).Code)
+	assert.False(t, m.Take(escalation))
+}
+
+func TestConfirmation(t *testing.T) {
+	m := newTestManager(t, filepath.Join(t.TempDir(), "state.json"), nil)
+
+	occurrence := dateutil.TodayStartOfDay()
+	link := m.URLs("uid:1234", occurrence, "pushover")[notifier.ActionAcknowledge]
+
+	// Opening the link (or a link scanner fetching it) only shows the
+	// confirmation page
+	w := serve(t, m, http.MethodGet, link)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `<form method="post">`)
+	assert.False(t, m.IsAcknowledged("uid:1234", occurrence))
+
+	// Invalid links are rejected before asking for confirmation
+	assert.Equal(t, http.StatusForbidden, serve(t, m, http.MethodGet, link+"x").Code)
+
+	w = serve(t, m, http.MethodPost, link)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "<form")
+	assert.True(t, m.IsAcknowledged("uid:1234", occurrence))
+}
+
+func TestInvalidLinks(t *testing.T) {
+	m := newTestManager(t, filepath.Join(t.TempDir(), "state.json"), nil)
+
+	occurrence := dateutil.TodayStartOfDay()
+	link := m.URLs("uid:1234", occurrence, "pushover")[notifier.ActionAcknowledge]
+
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+
+	// Tampered parameters
+	q := u.Query()
+	q.Set(paramContact, "uid:5678")
+	u.RawQuery = q.Encode()
+	assert.Equal(t, http.StatusForbidden, serve(t, m, http.MethodPost, u.String()).Code)
+
+	// Signature of another action
+	u, err = url.Parse(link)
+	require.NoError(t, err)
+	u.Path = "/api/action/snooze"
+	assert.Equal(t, http.StatusForbidden, serve(t, m, http.MethodPost, u.String()).Code)
+
+	// Expired link
+	u, err = url.Parse(link)
+	require.NoError(t, err)
+	q = u.Query()
+	q.Set(paramExpires, strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10))
+	q.Set(paramSignature, m.sign(notifier.ActionAcknowledge, q))
+	u.RawQuery = q.Encode()
+	assert.Equal(t, http.StatusForbidden, serve(t, m, http.MethodPost, u.String()).Code)
+
+	// Link signed with another secret
+	other, err := New(config.ActionsConfig{BaseURL: "https://example.com", Secret: "fedcba9876543210", StateFile: filepath.Join(t.TempDir(), "state.json")}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, serve(t, m, http.MethodPost, other.URLs("uid:1234", occurrence, "pushover")[notifier.ActionAcknowledge]).Code)
+
+	assert.False(t, m.IsAcknowledged("uid:1234", occurrence))
+}
+
+func TestContactKey(t *testing.T) {
+	assert.Equal(t, "uid:1234", ContactKey(vcard.Card{
+		vcard.FieldUID:           []*vcard.Field{{Value: "1234"}},
+		vcard.FieldFormattedName: []*vcard.Field{{Value: "Jane Doe"}},
+	}))
+	assert.Equal(t, "name:Jane Doe", ContactKey(vcard.Card{
+		vcard.FieldFormattedName: []*vcard.Field{{Value: "Jane Doe"}},
+	}))
+}
+
+func TestNilManager(t *testing.T) {
+	var m *Manager
+
+	assert.Nil(t, m.URLs("uid:1234", time.Now(), "pushover"))
+	assert.False(t, m.IsAcknowledged("uid:1234", time.Now()))
+	assert.Empty(t, m.PendingSnoozes())
+	assert.False(t, m.Take(Snooze{}))
+	assert.NoError(t, m.Escalate(Snooze{}))
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+
+	for _, cfg := range []config.ActionsConfig{
+		{Secret: testSecret, StateFile: stateFile},
+		{BaseURL: "/relative", Secret: testSecret, StateFile: stateFile},
+		{BaseURL: "https://example.com", Secret: "short", StateFile: stateFile},
+		{BaseURL: "https://example.com", Secret: testSecret, StateFile: stateFile, Snooze: -1},
+		{BaseURL: "https://example.com", Secret: testSecret},
+	} {
+		_, err := New(cfg, nil)
+		assert.Error(t, err)
+	}
+}
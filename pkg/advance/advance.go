@@ -0,0 +1,179 @@
+// Package advance contains a small rule language to express when to
+// send notifications relative to a birthday
+package advance
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	daysPerWeek      = 7
+	saturdayToMonday = 2
+)
+
+type (
+	// Rule describes when to notify relative to the date of a birthday
+	//
+	// Supported expressions:
+	//
+	//   - `3` / `3d`: 3 days before
+	//   - `2w`: 2 weeks before
+	//   - `1m`: 1 month before
+	//   - `friday-before`: the last Friday before (any weekday works)
+	//   - `next-working-day-after`: the Monday after for birthdays on a
+	//     weekend, nothing for birthdays on working days
+	Rule struct {
+		kind    ruleKind
+		n       int
+		raw     string
+		weekday time.Weekday
+	}
+
+	ruleKind uint8
+)
+
+const (
+	kindDays ruleKind = iota
+	kindMonths
+	kindWeekdayBefore
+	kindNextWorkingDayAfter
+)
+
+var (
+	// OnTheDay is the rule to notify on the birthday itself
+	OnTheDay = MustParse("0")
+
+	offsetFormat = regexp.MustCompile(`^(\d+)([dwm]?)$`)
+)
+
+// Parse parses the expression into a Rule
+func Parse(raw string) (r Rule, err error) {
+	expr := strings.ToLower(strings.TrimSpace(raw))
+	r.raw = expr
+
+	if m := offsetFormat.FindStringSubmatch(expr); m != nil {
+		if r.n, err = strconv.Atoi(m[1]); err != nil {
+			return r, fmt.Errorf("parsing number in %q: %w", raw, err)
+		}
+
+		switch m[2] {
+		case "", "d":
+			r.kind = kindDays
+		case "w":
+			r.kind, r.n = kindDays, r.n*daysPerWeek
+		case "m":
+			r.kind = kindMonths
+		}
+
+		return r, nil
+	}
+
+	if expr == "next-working-day-after" {
+		r.kind = kindNextWorkingDayAfter
+		return r, nil
+	}
+
+	if weekday, ok := strings.CutSuffix(expr, "-before"); ok {
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			if strings.EqualFold(d.String(), weekday) {
+				r.kind, r.weekday = kindWeekdayBefore, d
+				return r, nil
+			}
+		}
+	}
+
+	return r, fmt.Errorf("unknown advance rule %q", raw)
+}
+
+// MustParse parses the expression into a Rule and panics if it is
+// invalid. It is intended for rules known at compile time.
+func MustParse(raw string) Rule {
+	r, err := Parse(raw)
+	if err != nil {
+		panic(err)
+	}
+
+	return r
+}
+
+// ParseList parses a comma separated list of expressions
+func ParseList(raw string) ([]Rule, error) {
+	rules := []Rule{}
+
+	for _, expr := range strings.Split(raw, ",") {
+		if strings.TrimSpace(expr) == "" {
+			continue
+		}
+
+		r, err := Parse(expr)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+
+	return rules, nil
+}
+
+// NotifyDate calculates the date to notify at for the given occurrence
+// of the birthday. If the rule does not apply to this occurrence the
+// second return value is false.
+func (r Rule) NotifyDate(birthday time.Time) (time.Time, bool) {
+	birthday = time.Date(birthday.Year(), birthday.Month(), birthday.Day(), 0, 0, 0, 0, birthday.Location())
+
+	switch r.kind {
+	case kindDays:
+		return birthday.AddDate(0, 0, -r.n), true
+
+	case kindMonths:
+		// AddDate would normalize overflowing days (1 month before
+		// March 31st would be March 3rd) so we clamp to the last day of
+		// the target month instead
+		first := time.Date(birthday.Year(), birthday.Month()-time.Month(r.n), 1, 0, 0, 0, 0, birthday.Location())
+		lastDay := first.AddDate(0, 1, -1).Day()
+		return first.AddDate(0, 0, min(birthday.Day(), lastDay)-1), true
+
+	case kindWeekdayBefore:
+		diff := (int(birthday.Weekday()) - int(r.weekday) + daysPerWeek) % daysPerWeek
+		if diff == 0 {
+			diff = daysPerWeek
+		}
+		return birthday.AddDate(0, 0, -diff), true
+
+	case kindNextWorkingDayAfter:
+		switch birthday.Weekday() {
+		case time.Saturday:
+			return birthday.AddDate(0, 0, saturdayToMonday), true
+		case time.Sunday:
+			return birthday.AddDate(0, 0, 1), true
+		default:
+			return time.Time{}, false
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// String returns the normalized expression of the rule
+func (r Rule) String() string { return r.raw }
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface to parse
+// rules given as numbers (days) or expressions
+func (r *Rule) UnmarshalYAML(value *yaml.Node) (err error) {
+	var raw string
+	if err = value.Decode(&raw); err != nil {
+		return fmt.Errorf("decoding advance rule: %w", err)
+	}
+
+	if *r, err = Parse(raw); err != nil {
+		return fmt.Errorf("line %d: %w", value.Line, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package advance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.yaml.in/yaml/v3"
+)
+
+func TestNotifyDate(t *testing.T) {
+	date := func(m time.Month, d int) time.Time { return time.Date(2026, m, d, 0, 0, 0, 0, time.Local) }
+
+	for _, tc := range []struct {
+		Expr     string
+		Birthday time.Time
+		Expected time.Time
+		Applies  bool
+	}{
+		{Expr: "0", Birthday: date(3, 13), Expected: date(3, 13), Applies: true},
+		{Expr: "3", Birthday: date(3, 13), Expected: date(3, 10), Applies: true},
+		{Expr: "3d", Birthday: date(3, 2), Expected: date(2, 27), Applies: true},
+		{Expr: "2w", Birthday: date(3, 13), Expected: date(2, 27), Applies: true},
+		{Expr: "1m", Birthday: date(3, 13), Expected: date(2, 13), Applies: true},
+		// Month offsets are clamped to the end of the target month
+		{Expr: "1m", Birthday: date(3, 31), Expected: date(2, 28), Applies: true},
+		{Expr: "1m", Birthday: date(5, 31), Expected: date(4, 30), Applies: true},
+		{Expr: "3m", Birthday: date(5, 31), Expected: date(2, 28), Applies: true},
+		{Expr: "2m", Birthday: date(1, 31), Expected: time.Date(2025, 11, 30, 0, 0, 0, 0, time.Local), Applies: true},
+		{Expr: "1m", Birthday: time.Date(2028, 3, 31, 0, 0, 0, 0, time.Local), Expected: time.Date(2028, 2, 29, 0, 0, 0, 0, time.Local), Applies: true},
+		{Expr: "1m", Birthday: time.Date(2028, 2, 29, 0, 0, 0, 0, time.Local), Expected: time.Date(2028, 1, 29, 0, 0, 0, 0, time.Local), Applies: true},
+		{Expr: "12m", Birthday: time.Date(2028, 2, 29, 0, 0, 0, 0, time.Local), Expected: time.Date(2027, 2, 28, 0, 0, 0, 0, time.Local), Applies: true},
+		// 2026-03-13 is a Friday: The Friday before is a week earlier
+		{Expr: "friday-before", Birthday: date(3, 13), Expected: date(3, 6), Applies: true},
+		{Expr: "Friday-Before", Birthday: date(3, 16), Expected: date(3, 13), Applies: true},
+		{Expr: "sunday-before", Birthday: date(3, 13), Expected: date(3, 8), Applies: true},
+		// 2026-03-14 is a Saturday, 2026-03-15 a Sunday
+		{Expr: "next-working-day-after", Birthday: date(3, 14), Expected: date(3, 16), Applies: true},
+		{Expr: "next-working-day-after", Birthday: date(3, 15), Expected: date(3, 16), Applies: true},
+		{Expr: "next-working-day-after", Birthday: date(3, 13), Applies: false},
+	} {
+		r, err := Parse(tc.Expr)
+		require.NoError(t, err, tc.Expr)
+
+		d, ok := r.NotifyDate(tc.Birthday)
+		assert.Equal(t, tc.Applies, ok, tc.Expr)
+		if tc.Applies {
+			assert.Equal(t, tc.Expected, d, tc.Expr)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, expr := range []string{"", "-1", "2y", "tomorrow", "funday-before", "1.5w"} {
+		_, err := Parse(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestParseList(t *testing.T) {
+	rules, err := ParseList("3, 1w,friday-before")
+	require.NoError(t, err)
+	require.Len(t, rules, 3)
+	assert.Equal(t, "1w", rules[1].String())
+
+	rules, err = ParseList("")
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+
+	_, err = ParseList("3,soon")
+	assert.Error(t, err)
+}
+
+func TestUnmarshalYAML(t *testing.T) {
+	var v struct {
+		Rules []Rule `yaml:"rules"`
+	}
+
+	require.NoError(t, yaml.Unmarshal([]byte("rules: [ 1, 2w, friday-before ]"), &v))
+	require.Len(t, v.Rules, 3)
+	assert.Equal(t, "1", v.Rules[0].String())
+	assert.Equal(t, "2w", v.Rules[1].String())
+
+	assert.Error(t, yaml.Unmarshal([]byte("rules: [ someday ]"), &v))
+}
@@ -3,45 +3,198 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/Luzifer/go_helpers/fieldcollection"
 	"github.com/sirupsen/logrus"
 	"go.yaml.in/yaml/v3"
 
-	"git.luzifer.io/luzifer/birthday-notifier/pkg/formatter"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/advance"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/i18n"
 )
 
 // WebdavPrincipalNextcloud is the principal default used for config
 // files on parse and represents the principal format used by Nextcloud
 const WebdavPrincipalNextcloud = "principals/users/%s"
 
+const (
+	defaultFetchInterval = time.Hour
+	defaultStateFileName = "deliveries.json"
+)
+
 type (
 	// File contains the structure of the YAML configuration file
 	File struct {
-		NotifyDaysInAdvance []int            `yaml:"notifyDaysInAdvance"`
+		// Accounts contains the CardDAV accounts to fetch contacts from,
+		// if not given the Webdav account is used
+		Accounts []WebdavConfig `yaml:"accounts"`
+
+		Actions *ActionsConfig `yaml:"actions"`
+
+		AppendNotes []string `yaml:"appendNotes"`
+
+		Deduplicate DeduplicateConfig `yaml:"deduplicate"`
+
+		Greeting *GreetingConfig `yaml:"greeting"`
+
+		Locale string `yaml:"locale"`
+
+		Milestones *MilestoneConfig `yaml:"milestones"`
+
+		NotifyDaysInAdvance []advance.Rule   `yaml:"notifyDaysInAdvance"`
 		Notifiers           []NotifierConfig `yaml:"notifiers"`
 
-		Template string `yaml:"template"`
+		Photos *PhotoConfig `yaml:"photos"`
+
+		Routes []RouteConfig `yaml:"routes"`
 
+		// StateFile stores the deliveries deferred by quiet hours /
+		// blackout dates and the greetings waiting for their time,
+		// defaults to a file next to the state file of the actions
+		StateFile string `yaml:"stateFile"`
+
+		Template    string `yaml:"template"`
+		TemplateDir string `yaml:"templateDir"`
+
+		// Webdav contains the account to use if only one is needed,
+		// Load copies it into Accounts
 		Webdav WebdavConfig `yaml:"webdav"`
 	}
 
+	// ActionsConfig enables acknowledging and snoozing notifications
+	// through signed links pointing to the HTTP API
+	ActionsConfig struct {
+		BaseURL   string        `yaml:"baseURL"`
+		Secret    string        `yaml:"secret"`
+		Snooze    time.Duration `yaml:"snooze"`
+		StateFile string        `yaml:"stateFile"`
+	}
+
+	// DeduplicateConfig defines how to handle the same person being
+	// present multiple times (i.e. in a personal and a shared address
+	// book)
+	DeduplicateConfig struct {
+		Policy             string   `yaml:"policy"`
+		PreferAddressBooks []string `yaml:"preferAddressBooks"`
+	}
+
+	// GreetingConfig configures sending a greeting to the contact
+	// having their birthday instead of notifying the user
+	GreetingConfig struct {
+		Mode          string `yaml:"mode"`
+		OptInCategory string `yaml:"optInCategory"`
+		OptInProperty string `yaml:"optInProperty"`
+		SendAt        string `yaml:"sendAt"`
+		Template      string `yaml:"template"`
+
+		Email *GreetingEmailConfig `yaml:"email"`
+		SMS   *GreetingSMSConfig   `yaml:"sms"`
+	}
+
+	// GreetingEmailConfig defines how to send greetings through SMTP,
+	// the password can be given directly, as file or as command
+	GreetingEmailConfig struct {
+		From        string `yaml:"from"`
+		Host        string `yaml:"host"`
+		Pass        string `yaml:"pass"`
+		PassCommand string `yaml:"passCommand"`
+		PassFile    string `yaml:"passFile"`
+		Port        int    `yaml:"port"`
+		Subject     string `yaml:"subject"`
+		User        string `yaml:"user"`
+	}
+
+	// GreetingSMSConfig defines how to send greetings through a SMS
+	// gateway webhook
+	GreetingSMSConfig struct {
+		Headers map[string]string `yaml:"headers"`
+		Webhook string            `yaml:"webhook"`
+	}
+
+	// MilestoneConfig defines which ages are milestones and how to
+	// notify about birthdays reaching them
+	MilestoneConfig struct {
+		Ages                []int          `yaml:"ages"`
+		Every               int            `yaml:"every"`
+		NotifyDaysInAdvance []advance.Rule `yaml:"notifyDaysInAdvance"`
+		Template            string         `yaml:"template"`
+	}
+
 	// NotifierConfig contains the type of the notifier and the settings
 	// for it required to execute
 	NotifierConfig struct {
+		Enabled  *bool                            `yaml:"enabled"`
+		Locale   string                           `yaml:"locale"`
+		Name     string                           `yaml:"name"`
 		Type     string                           `yaml:"type"`
 		Settings *fieldcollection.FieldCollection `yaml:"settings"`
+
+		// Templates maps event types (`advance`, `today`, `after`,
+		// `milestone` or `default` for all others) to the names of the
+		// templates loaded from the TemplateDir
+		Templates map[string]string `yaml:"templates"`
+
+		BlackoutDates []string          `yaml:"blackoutDates"`
+		QuietHours    *QuietHoursConfig `yaml:"quietHours"`
+	}
+
+	// PhotoConfig defines how to load the PHOTO of the contacts to
+	// attach it to notifications
+	PhotoConfig struct {
+		Fetch    bool          `yaml:"fetch"`
+		MaxBytes int64         `yaml:"maxBytes"`
+		Size     int           `yaml:"size"`
+		Timeout  time.Duration `yaml:"timeout"`
+	}
+
+	// QuietHoursConfig defines a daily time window (`15:04` format) in
+	// which no notifications are delivered. The window may span midnight.
+	QuietHoursConfig struct {
+		End   string `yaml:"end"`
+		Start string `yaml:"start"`
+	}
+
+	// EscalationStepConfig defines which notifiers to notify additionally
+	// if a notification has not been acknowledged after some time
+	EscalationStepConfig struct {
+		After     time.Duration `yaml:"after"`
+		Notifiers []string      `yaml:"notifiers"`
+	}
+
+	// RouteConfig defines which notifiers to use for contacts matching
+	// the given criteria
+	RouteConfig struct {
+		Escalation          []EscalationStepConfig `yaml:"escalation"`
+		Match               RouteMatchConfig       `yaml:"match"`
+		Notifiers           []string               `yaml:"notifiers"`
+		NotifyDaysInAdvance []advance.Rule         `yaml:"notifyDaysInAdvance"`
+	}
+
+	// RouteMatchConfig contains the criteria to match a contact against.
+	// All given criteria must match, within one criterion any of the
+	// values must match.
+	RouteMatchConfig struct {
+		Accounts      []string `yaml:"accounts"`
+		AddressBooks  []string `yaml:"addressBooks"`
+		Categories    []string `yaml:"categories"`
+		NameRegex     string   `yaml:"nameRegex"`
+		Organizations []string `yaml:"organizations"`
 	}
 
-	// WebdavConfig defines how to interact with the Webdav server
+	// WebdavConfig defines how to interact with the Webdav server, the
+	// password can be given directly, as file or as command
 	WebdavConfig struct {
 		BaseURL       string        `yaml:"baseURL"`
 		FetchInterval time.Duration `yaml:"fetchInterval"`
+		Label         string        `yaml:"label"`
 		Pass          string        `yaml:"pass"`
+		PassCommand   string        `yaml:"passCommand"`
+		PassFile      string        `yaml:"passFile"`
 		Principal     string        `yaml:"principal"`
 		User          string        `yaml:"user"`
 	}
@@ -51,13 +204,76 @@ type (
 // the fields specified in the reader
 func Load(r io.Reader) (f File, err error) {
 	f = defaultConfig()
-	dec := yaml.NewDecoder(r)
+
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return f, fmt.Errorf("reading config: %w", err)
+	}
+
+	if raw, err = expandEnv(raw); err != nil {
+		return f, fmt.Errorf("expanding environment variables: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
 
 	dec.KnownFields(true)
 	if err = dec.Decode(&f); err != nil {
 		return f, fmt.Errorf("decoding yaml: %w", err)
 	}
 
+	switch {
+	case len(f.Accounts) == 0:
+		f.Accounts = []WebdavConfig{f.Webdav}
+
+	case f.Webdav.BaseURL != "":
+		return f, fmt.Errorf("webdav and accounts must not be used together")
+	}
+
+	for i := range f.Accounts {
+		if f.Accounts[i].FetchInterval == 0 {
+			f.Accounts[i].FetchInterval = defaultFetchInterval
+		}
+
+		if f.Accounts[i].Principal == "" {
+			f.Accounts[i].Principal = WebdavPrincipalNextcloud
+		}
+
+		if f.Accounts[i].Pass, err = resolvePass(f.Accounts[i].Pass, f.Accounts[i].PassFile, f.Accounts[i].PassCommand); err != nil {
+			return f, fmt.Errorf("account %d: %w", i, err)
+		}
+
+		if f.Accounts[i].Label == "" {
+			f.Accounts[i].Label = f.Accounts[i].User
+		}
+
+		for j := range i {
+			if f.Accounts[j].Label == f.Accounts[i].Label {
+				return f, fmt.Errorf("accounts %d and %d have the same label %q", j, i, f.Accounts[i].Label)
+			}
+		}
+	}
+
+	if f.Greeting != nil && f.Greeting.Email != nil {
+		email := f.Greeting.Email
+		if email.Pass, err = resolvePass(email.Pass, email.PassFile, email.PassCommand); err != nil {
+			return f, fmt.Errorf("greeting email: %w", err)
+		}
+	}
+
+	if f.StateFile == "" && f.Actions != nil && f.Actions.StateFile != "" {
+		f.StateFile = filepath.Join(filepath.Dir(f.Actions.StateFile), defaultStateFileName)
+	}
+
+	for i := range f.Notifiers {
+		if f.Notifiers[i].Name == "" {
+			f.Notifiers[i].Name = fmt.Sprintf("%s-%d", f.Notifiers[i].Type, i)
+		}
+
+		if f.Notifiers[i].Locale == "" {
+			f.Notifiers[i].Locale = f.Locale
+		}
+	}
+
 	return f, nil
 }
 
@@ -77,14 +293,24 @@ func LoadFromFile(filePath string) (f File, err error) {
 	return Load(inFile)
 }
 
+// IsEnabled returns whether the notifier is enabled, which is the
+// default if not specified otherwise
+func (n NotifierConfig) IsEnabled() bool {
+	return n.Enabled == nil || *n.Enabled
+}
+
 func defaultConfig() File {
 	return File{
-		NotifyDaysInAdvance: nil,
+		Deduplicate: DeduplicateConfig{
+			Policy: "prefer",
+		},
 
-		Template: formatter.DefaultTemplate,
+		Locale: i18n.DefaultLocale,
+
+		NotifyDaysInAdvance: nil,
 
 		Webdav: WebdavConfig{
-			FetchInterval: time.Hour,
+			FetchInterval: defaultFetchInterval,
 			Principal:     WebdavPrincipalNextcloud,
 		},
 	}
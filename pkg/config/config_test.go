@@ -0,0 +1,91 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/secrets"
+)
+
+func TestLoadExpandEnv(t *testing.T) {
+	t.Setenv("BN_TEST_API_TOKEN", "pushover-api-token")
+	t.Setenv("BN_TEST_PORT", "587")
+
+	passFile := filepath.Join(t.TempDir(), "dav")
+	require.NoError(t, os.WriteFile(passFile, []byte("dav-password\n"), 0o600))
+
+	f, err := Load(strings.NewReader(`
+greeting:
+  mode: dry-run
+  email:
+    port: ${BN_TEST_PORT}
+    passCommand: echo smtp-password
+notifiers:
+  - type: exec
+    settings:
+      command: /bin/sh
+      args: ['-c', 'notify-send "${BIRTHDAY_TITLE}"']
+  - type: pushover
+    settings:
+      apiToken: ${BN_TEST_API_TOKEN}
+      userKey: 'plain-user-key'
+      url: 'https://example.com/$${literal}'
+webdav:
+  baseURL: https://dav.example.com/
+  user: me
+  passFile: ` + passFile + `
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, 587, f.Greeting.Email.Port)
+	assert.Equal(t, "smtp-password", f.Greeting.Email.Pass)
+	assert.Equal(t, "pushover-api-token", f.Notifiers[1].Settings.MustString("apiToken", nil))
+	assert.Equal(t, "https://example.com/${literal}", f.Notifiers[1].Settings.MustString("url", nil))
+	assert.Equal(t, "dav-password", f.Accounts[0].Pass)
+	assert.Equal(t, []string{"-c", `notify-send "${BIRTHDAY_TITLE}"`}, f.Notifiers[0].Settings.MustStringSlice("args", nil))
+
+	assert.Equal(t,
+		"[redacted] [redacted] [redacted] [redacted] me",
+		secrets.Redact("pushover-api-token plain-user-key dav-password smtp-password me"))
+}
+
+func TestLoadSecretErrors(t *testing.T) {
+	_, err := Load(strings.NewReader("webdav:\n  pass: ${BN_TEST_UNSET}\n"))
+	assert.ErrorContains(t, err, "BN_TEST_UNSET")
+
+	_, err = Load(strings.NewReader("webdav:\n  pass: secret\n  passCommand: echo secret\n"))
+	assert.Error(t, err)
+}
+
+func TestLoadAccounts(t *testing.T) {
+	f, err := Load(strings.NewReader("webdav:\n  baseURL: https://dav.example.com/\n  user: me\n"))
+	require.NoError(t, err)
+	require.Len(t, f.Accounts, 1)
+	assert.Equal(t, "me", f.Accounts[0].Label)
+	assert.Equal(t, WebdavPrincipalNextcloud, f.Accounts[0].Principal)
+
+	_, err = Load(strings.NewReader("accounts:\n  - user: me\n  - user: me\n"))
+	assert.Error(t, err, "duplicate label")
+
+	_, err = Load(strings.NewReader("webdav:\n  baseURL: https://dav.example.com/\naccounts:\n  - user: me\n"))
+	assert.Error(t, err, "webdav and accounts")
+}
+
+func TestLoadStateFile(t *testing.T) {
+	f, err := Load(strings.NewReader("actions:\n  stateFile: /var/lib/birthday-notifier/actions.json\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "/var/lib/birthday-notifier/deliveries.json", f.StateFile)
+
+	f, err = Load(strings.NewReader("actions:\n  stateFile: /var/lib/birthday-notifier/actions.json\nstateFile: /tmp/pending.json\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/pending.json", f.StateFile)
+
+	f, err = Load(strings.NewReader("locale: en\n"))
+	require.NoError(t, err)
+	assert.Empty(t, f.StateFile)
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"fmt"
+
+	"go.yaml.in/yaml/v3"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/secrets"
+)
+
+// expandEnv replaces `${VAR}` references in all string values of the
+// YAML document (including the notifier settings) and registers the
+// values of sensitive settings for redaction. References to unset
+// variables are kept (i.e. for `exec` args run through a shell) except
+// in sensitive settings. The document is only re-encoded if references
+// were found to keep line numbers in errors.
+func expandEnv(raw []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("decoding yaml: %w", err)
+	}
+
+	changed, err := expandNode(&doc, "")
+	if err != nil || !changed {
+		return raw, err
+	}
+
+	if raw, err = yaml.Marshal(&doc); err != nil {
+		return nil, fmt.Errorf("encoding yaml: %w", err)
+	}
+
+	return raw, nil
+}
+
+// expandNode walks the node and its children: key is the key of the
+// mapping the node is the value of
+func expandNode(node *yaml.Node, key string) (changed bool, err error) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			c, err := expandNode(child, key)
+			if err != nil {
+				return false, err
+			}
+			changed = changed || c
+		}
+
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			c, err := expandNode(node.Content[i+1], node.Content[i].Value)
+			if err != nil {
+				return false, err
+			}
+			changed = changed || c
+		}
+
+	case yaml.ScalarNode:
+		if node.ShortTag() == "!!str" {
+			// An unset variable in a secret would silently be used as the
+			// secret itself, therefore they must be set
+			value, expanded, err := secrets.ExpandEnv(node.Value, secrets.IsSensitiveKey(key))
+			if err != nil {
+				return false, fmt.Errorf("line %d: %w", node.Line, err)
+			}
+
+			if value != node.Value || expanded {
+				node.Value, changed = value, true
+			}
+
+			if expanded && node.Style == 0 {
+				// Unquoted values are resolved again to allow numbers and
+				// booleans to be given through variables
+				node.Tag = ""
+			}
+		}
+
+		if secrets.IsSensitiveKey(key) {
+			secrets.Register(node.Value)
+		}
+	}
+
+	return changed, nil
+}
+
+// resolvePass returns the password given directly or read from the file
+// or the output of the command
+func resolvePass(pass, passFile, passCommand string) (string, error) {
+	var given int
+	for _, v := range []string{pass, passFile, passCommand} {
+		if v != "" {
+			given++
+		}
+	}
+
+	switch {
+	case given > 1:
+		return "", fmt.Errorf("only one of pass, passFile and passCommand may be set")
+
+	case passFile != "":
+		return secrets.FromFile(passFile)
+
+	case passCommand != "":
+		return secrets.FromCommand(passCommand)
+
+	default:
+		return pass, nil
+	}
+}
@@ -0,0 +1,91 @@
+package dateutil
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type (
+	// Birthday represents a date of birth with an optional year
+	Birthday struct {
+		Day   int
+		Month time.Month
+		// Year of birth, zero if unknown
+		Year int
+	}
+)
+
+// unknownYear is used as placeholder year for birthdays without year.
+// It needs to be a leap year for February 29th to stay intact.
+const unknownYear = 4
+
+// ErrYearUnknown signals the year of birth is not known and therefore
+// the age cannot be calculated
+var ErrYearUnknown = errors.New("year of birth is unknown")
+
+// NewBirthday creates a Birthday from the given date. Dates in the
+// year 1 or before are treated as having no known year as the parser
+// uses the year 1 for birthdays without year.
+func NewBirthday(t time.Time) Birthday {
+	b := Birthday{Day: t.Day(), Month: t.Month()}
+	if t.Year() > 1 {
+		b.Year = t.Year()
+	}
+
+	return b
+}
+
+// Age returns the age at the given date. If the year of birth is not
+// known the second return value is false.
+func (b Birthday) Age(at time.Time) (int, bool) {
+	if !b.HasYear() {
+		return 0, false
+	}
+
+	age := at.Year() - b.Year
+	if at.Month() < b.Month || (at.Month() == b.Month && at.Day() < b.Day) {
+		age--
+	}
+
+	return age, true
+}
+
+// Format formats the date of birth using the given layout. If the year
+// is not known the year 4 is used.
+func (b Birthday) Format(layout string) string {
+	return b.Time().Format(layout)
+}
+
+// HasYear reports whether the year of birth is known
+func (b Birthday) HasYear() bool { return b.Year > 0 }
+
+// IsToday checks whether the birthday is today
+func (b Birthday) IsToday() bool { return IsToday(b.Time()) }
+
+// NextOccurrence returns the next birthday being today or later
+func (b Birthday) NextOccurrence() time.Time { return ProjectToNextBirthday(b.Time()) }
+
+// PreviousOccurrence returns the last birthday before today
+func (b Birthday) PreviousOccurrence() time.Time { return ProjectToPreviousBirthday(b.Time()) }
+
+// String returns the birthday as `2006-01-02` or as `--01-02` if the
+// year is not known
+func (b Birthday) String() string {
+	if !b.HasYear() {
+		return fmt.Sprintf("--%02d-%02d", b.Month, b.Day)
+	}
+
+	return b.Format(time.DateOnly)
+}
+
+// Time returns the date of birth as time.Time in the local timezone
+// using the (leap) year 4 if the year is not known
+func (b Birthday) Time() time.Time {
+	year := b.Year
+	if !b.HasYear() {
+		year = unknownYear
+	}
+
+	return time.Date(year, b.Month, b.Day, 0, 0, 0, 0, time.Local)
+}
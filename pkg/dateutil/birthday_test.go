@@ -0,0 +1,68 @@
+package dateutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/emersion/go-vcard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBirthday(t *testing.T) {
+	known := NewBirthday(time.Date(1990, 3, 13, 0, 0, 0, 0, time.Local))
+	assert.True(t, known.HasYear())
+	assert.Equal(t, "1990-03-13", known.String())
+
+	age, ok := known.Age(time.Date(2026, 3, 12, 0, 0, 0, 0, time.Local))
+	assert.True(t, ok)
+	assert.Equal(t, 35, age)
+
+	age, ok = known.Age(time.Date(2026, 3, 13, 0, 0, 0, 0, time.Local))
+	assert.True(t, ok)
+	assert.Equal(t, 36, age)
+
+	unknown := NewBirthday(time.Date(1, 3, 13, 0, 0, 0, 0, time.Local))
+	assert.False(t, unknown.HasYear())
+	assert.Equal(t, 0, unknown.Year)
+	assert.Equal(t, "--03-13", unknown.String())
+	assert.Equal(t, time.Date(4, 3, 13, 0, 0, 0, 0, time.Local), unknown.Time())
+
+	_, ok = unknown.Age(time.Date(2026, 3, 13, 0, 0, 0, 0, time.Local))
+	assert.False(t, ok)
+}
+
+func TestBirthdayOccurrences(t *testing.T) {
+	today := NewBirthday(time.Now().AddDate(-30, 0, 0))
+
+	assert.True(t, today.IsToday())
+	assert.Equal(t, TodayStartOfDay(), today.NextOccurrence())
+	assert.Equal(t, TodayStartOfDay().AddDate(-1, 0, 0), today.PreviousOccurrence())
+}
+
+func TestBirthdayLeapDayWithoutYear(t *testing.T) {
+	b, err := Parse(&vcard.Field{Value: "--0229"})
+	require.NoError(t, err)
+	assert.Equal(t, Birthday{Day: 29, Month: time.February}, b)
+	assert.Equal(t, "--02-29", b.String())
+
+	bt := b.Time()
+	assert.Equal(t, time.February, bt.Month())
+	assert.Equal(t, 29, bt.Day())
+
+	// Projection into a leap year keeps the date, in other years it
+	// moves to March 1st
+	assert.Equal(t,
+		time.Date(2028, time.February, 29, 0, 0, 0, 0, time.Local),
+		time.Date(2028, bt.Month(), bt.Day(), 0, 0, 0, 0, time.Local))
+	assert.Equal(t,
+		time.Date(2027, time.March, 1, 0, 0, 0, 0, time.Local),
+		time.Date(2027, bt.Month(), bt.Day(), 0, 0, 0, 0, time.Local))
+
+	if next := b.NextOccurrence(); next.Month() == time.February {
+		assert.Equal(t, 29, next.Day())
+	} else {
+		assert.Equal(t, time.March, next.Month())
+		assert.Equal(t, 1, next.Day())
+	}
+}
@@ -1,6 +1,18 @@
 package dateutil
 
-import "time"
+import (
+	"math"
+	"time"
+)
+
+const hoursPerDay = 24
+
+// DaysUntil returns the number of days from today until the given
+// date (0 being today, negative for dates in the past)
+func DaysUntil(t time.Time) int {
+	t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+	return int(math.Round(t.Sub(TodayStartOfDay()).Hours() / hoursPerDay))
+}
 
 // IsToday uses ProjectToNextBirthday to get the next birthday and
 // compares it to TodayStartOfDay
@@ -19,6 +31,12 @@ func ProjectToNextBirthday(t time.Time) time.Time {
 	return projected
 }
 
+// ProjectToPreviousBirthday takes a birth date and projects it to the
+// last birthday before today
+func ProjectToPreviousBirthday(t time.Time) time.Time {
+	return time.Date(ProjectToNextBirthday(t).Year()-1, t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
 // TodayStartOfDay gets the start of the current day
 func TodayStartOfDay() time.Time {
 	return time.Date(time.Now().Year(), time.Now().Month(), time.Now().Day(), 0, 0, 0, 0, time.Local)
@@ -9,6 +9,28 @@ import (
 
 const timeDay = 24 * time.Hour
 
+func TestDaysUntil(t *testing.T) {
+	assert.Equal(t, 0, DaysUntil(time.Now()))
+	assert.Equal(t, 2, DaysUntil(TodayStartOfDay().AddDate(0, 0, 2)))
+	assert.Equal(t, -3, DaysUntil(TodayStartOfDay().AddDate(0, 0, -3)))
+}
+
+func TestProjectToPreviousBirthday(t *testing.T) {
+	// Today's birthday is the next, not the previous one
+	assert.Equal(
+		t,
+		time.Now().Year()-1,
+		ProjectToPreviousBirthday(time.Date(1990, time.Now().Month(), time.Now().Day(), 0, 0, 0, 0, time.Local)).Year(),
+	)
+
+	// Yesterday stays in the year
+	assert.Equal(
+		t,
+		TodayStartOfDay().AddDate(0, 0, -1),
+		ProjectToPreviousBirthday(time.Now().AddDate(-30, 0, -1)),
+	)
+}
+
 func TestProjectToNextBirthday(t *testing.T) {
 	// Now should stay in the year
 	assert.Equal(
@@ -0,0 +1,71 @@
+package dateutil
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/emersion/go-vcard"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/advance"
+)
+
+// Custom vCard properties to control the notifications for a contact
+// from within the address book
+const (
+	FieldAdvance   = "X-BIRTHDAY-ADVANCE"
+	FieldNotifiers = "X-BIRTHDAY-NOTIFIERS"
+	FieldNotify    = "X-BIRTHDAY-NOTIFY"
+)
+
+type (
+	// Overrides contains the per-contact notification settings read
+	// from custom vCard properties. Nil-slices signal the property was
+	// not set and the configured defaults should be used.
+	Overrides struct {
+		Advance   []advance.Rule
+		Mute      bool
+		Notifiers []string
+	}
+)
+
+// ParseOverrides reads the custom properties from the contact. In case
+// of an error the returned Overrides contain all properties which
+// could be parsed.
+func ParseOverrides(contact vcard.Card) (o Overrides, err error) {
+	var errs []string
+
+	if v := contact.Get(FieldNotify); v != nil {
+		switch strings.ToLower(strings.TrimSpace(v.Value)) {
+		case "0", "false", "no", "off":
+			o.Mute = true
+		case "1", "true", "yes", "on":
+			o.Mute = false
+		default:
+			errs = append(errs, fmt.Sprintf("%s: unknown value %q", FieldNotify, v.Value))
+		}
+	}
+
+	if v := contact.Get(FieldAdvance); v != nil {
+		rules, err := advance.ParseList(v.Value)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", FieldAdvance, err))
+		} else {
+			o.Advance = rules
+		}
+	}
+
+	if v := contact.Get(FieldNotifiers); v != nil {
+		o.Notifiers = []string{}
+		for _, n := range strings.Split(v.Value, ",") {
+			if n = strings.TrimSpace(n); n != "" {
+				o.Notifiers = append(o.Notifiers, n)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return o, fmt.Errorf("parsing overrides: %s", strings.Join(errs, ", "))
+	}
+
+	return o, nil
+}
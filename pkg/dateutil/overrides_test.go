@@ -0,0 +1,62 @@
+package dateutil
+
+import (
+	"testing"
+
+	"github.com/emersion/go-vcard"
+	"github.com/stretchr/testify/assert"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/advance"
+)
+
+func TestParseOverrides(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Card      vcard.Card
+		Expected  Overrides
+		ExpectErr bool
+	}{
+		"none": {
+			Card: vcard.Card{},
+		},
+		"muted": {
+			Card:     vcard.Card{FieldNotify: {{Value: "Off"}}},
+			Expected: Overrides{Mute: true},
+		},
+		"enabled": {
+			Card: vcard.Card{FieldNotify: {{Value: "on"}}},
+		},
+		"advance and notifiers": {
+			Card: vcard.Card{
+				FieldAdvance:   {{Value: "3, 1w, friday-before"}},
+				FieldNotifiers: {{Value: "slack-team,pushover"}},
+			},
+			Expected: Overrides{
+				Advance:   []advance.Rule{advance.MustParse("3"), advance.MustParse("1w"), advance.MustParse("friday-before")},
+				Notifiers: []string{"slack-team", "pushover"},
+			},
+		},
+		"empty advance disables advance notifications": {
+			Card:     vcard.Card{FieldAdvance: {{Value: ""}}},
+			Expected: Overrides{Advance: []advance.Rule{}},
+		},
+		"invalid values keep valid ones": {
+			Card: vcard.Card{
+				FieldAdvance:   {{Value: "3,tomorrow"}},
+				FieldNotify:    {{Value: "maybe"}},
+				FieldNotifiers: {{Value: "log"}},
+			},
+			Expected:  Overrides{Notifiers: []string{"log"}},
+			ExpectErr: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			o, err := ParseOverrides(tc.Card)
+			if tc.ExpectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.Expected, o)
+		})
+	}
+}
@@ -1,21 +1,68 @@
-// Package dateutil contains a helper to parse vcard dates
+// Package dateutil contains helpers to parse vcard dates and the
+// birthday related properties of a contact
 package dateutil
 
 import (
+	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/emersion/go-vcard"
 )
 
-// Parse parses a vcard.Field into a time.Time
-func Parse(field *vcard.Field) (d time.Time, err error) {
+type (
+	// dateForm describes one of the supported representations of the
+	// date part of a birthday
+	dateForm struct {
+		layout string
+		// reduced is set for forms lacking the month or the day so the
+		// birthday is understood but cannot be scheduled
+		reduced bool
+	}
+)
+
+// ErrNotSchedulable signals the birthday was understood but does not
+// contain enough information (month and day) to schedule notifications
+var ErrNotSchedulable = errors.New("birthday cannot be scheduled")
+
+var (
+	// dateForms contains the supported date representations. As we
+	// can't rely on `VALUE=DATE` being set (thanks Sabre) we're trying
+	// to walk them in order until we found a matching one…
+	dateForms = []dateForm{
+		{layout: "20060102"},               // RFC 6350 basic format, most likely, test first
+		{layout: "2006-01-02"},             // Extended format, used by Apple, Google and Outlook
+		{layout: "060102"},                 // Basic format with 2-digit year
+		{layout: "--0102"},                 // RFC 6350 omitted year
+		{layout: "--01-02"},                // Extended format with omitted year
+		{layout: "2006-01", reduced: true}, // RFC 6350 reduced accuracy: year and month
+		{layout: "2006", reduced: true},    // RFC 6350 reduced accuracy: year only
+		{layout: "--01", reduced: true},    // RFC 6350 reduced accuracy: month only
+		{layout: "---02", reduced: true},   // RFC 6350 reduced accuracy: day only
+	}
+
+	// timeForm matches the time part of a DATE-TIME in basic or
+	// extended format with optional fractional seconds and an optional
+	// `Z` or `+hh[mm]` offset
+	timeForm = regexp.MustCompile(`^\d{2}(:?\d{2}(:?\d{2}(\.\d+)?)?)?(Z|[+-]\d{2}(:?\d{2})?)?$`)
+)
+
+// Parse parses a vcard.Field into a Birthday. Birthdays which are
+// understood but cannot be scheduled (text values, reduced accuracy
+// without month or day) yield an error wrapping ErrNotSchedulable.
+func Parse(field *vcard.Field) (Birthday, error) {
 	if field == nil {
-		return d, fmt.Errorf("nil-field given")
+		return Birthday{}, fmt.Errorf("nil-field given")
 	}
 
-	rawDate := field.Value
+	rawDate := strings.TrimSpace(field.Value)
+
+	if strings.EqualFold(field.Params.Get(vcard.ParamValue), "text") {
+		// vCard 4 allows free-form text like "circa 1800"
+		return Birthday{}, fmt.Errorf("%w: text value %q", ErrNotSchedulable, rawDate)
+	}
 
 	if field.Params.Get("X-APPLE-OMIT-YEAR") != "" {
 		// Yay, Apple bullshit. They don't use the proper way defined in
@@ -29,29 +76,32 @@ func Parse(field *vcard.Field) (d time.Time, err error) {
 		rawDate = strings.Replace(rawDate, field.Params.Get("X-APPLE-OMIT-YEAR"), "0001", 1)
 	}
 
-	// And now as we can't rely on `VALUE=DATE` being set (thanks Sabre)
-	// we're trying to walk possible formats until we found a matching
-	// one…
-
-	for _, fmtCandidate := range []string{
-		"20060102",        // Most likely, test first
-		"2006-01-02",      // Invalid as of RFC, used by Apple, see above
-		"060102",          // RFC compliant with 2-digit year
-		"--0102",          // RFC-compliant omit-year
-		"2006-01",         // Shouldn't exist as no day present but is valid
-		"2006",            // Somewhere in the year
-		"20060102T150405", // Full DATE-TIME in RFC
-		"20060102T1504",   // DATE-TIME in RFC without seconds
-		"20060102T15",     // DATE-TIME in RFC without minutes & seconds
-	} {
-		d, err = time.ParseInLocation(fmtCandidate, rawDate, time.Local)
-		if err == nil {
-			// Yay! It matched. Or at least it had the right length and numbers
-			// at the right places so it SHOULD have matched.
-			return d, nil
+	// DATE-TIME values and ISO strings exported by Google / Outlook carry
+	// a time we're not interested in: The birthday is the date as it is
+	// written down, we do not convert it between time zones as this
+	// would move the birthday to another day.
+	if datePart, timePart, ok := strings.Cut(rawDate, "T"); ok {
+		if !timeForm.MatchString(timePart) {
+			return Birthday{}, fmt.Errorf("invalid time %q in %q", timePart, rawDate)
+		}
+		rawDate = datePart
+	}
+
+	for _, form := range dateForms {
+		d, err := time.ParseInLocation(form.layout, rawDate, time.Local)
+		if err != nil {
+			continue
+		}
+
+		// Yay! It matched. Or at least it had the right length and numbers
+		// at the right places so it SHOULD have matched.
+		if form.reduced {
+			return Birthday{}, fmt.Errorf("%w: %q lacks month or day", ErrNotSchedulable, rawDate)
 		}
+
+		return NewBirthday(d), nil
 	}
 
 	// Well. We found no matching format.
-	return d, fmt.Errorf("no format defined for %q", rawDate)
+	return Birthday{}, fmt.Errorf("no format defined for %q", rawDate)
 }
@@ -6,16 +6,17 @@ import (
 
 	"github.com/emersion/go-vcard"
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
 )
 
 func TestParseDate(t *testing.T) {
-	for _, tc := range []struct {
-		ExpectedTime time.Time
-		Field        *vcard.Field
+	for name, tc := range map[string]struct {
+		Expected       Birthday
+		Field          *vcard.Field
+		ExpectErr      bool
+		NotSchedulable bool
 	}{
-		{
-			ExpectedTime: time.Date(2000, 2, 5, 0, 0, 0, 0, time.Local),
+		"basic date": {
+			Expected: Birthday{Day: 5, Month: time.February, Year: 2000},
 			Field: &vcard.Field{
 				Value: "20000205",
 				Params: vcard.Params{
@@ -23,8 +24,16 @@ func TestParseDate(t *testing.T) {
 				},
 			},
 		},
-		{
-			ExpectedTime: time.Date(1, 2, 15, 0, 0, 0, 0, time.Local),
+		"extended date": {
+			Expected: Birthday{Day: 5, Month: time.February, Year: 2000},
+			Field:    &vcard.Field{Value: "2000-02-05"},
+		},
+		"2-digit year": {
+			Expected: Birthday{Day: 5, Month: time.February, Year: 2000},
+			Field:    &vcard.Field{Value: "000205"},
+		},
+		"apple omit year": {
+			Expected: Birthday{Day: 15, Month: time.February},
 			Field: &vcard.Field{
 				Value: "1604-02-15",
 				Params: vcard.Params{
@@ -32,8 +41,8 @@ func TestParseDate(t *testing.T) {
 				},
 			},
 		},
-		{
-			ExpectedTime: time.Date(1, 11, 2, 0, 0, 0, 0, time.Local),
+		"apple omit year with value": {
+			Expected: Birthday{Day: 2, Month: time.November},
 			Field: &vcard.Field{
 				Value: "20221102",
 				Params: vcard.Params{
@@ -42,9 +51,89 @@ func TestParseDate(t *testing.T) {
 				},
 			},
 		},
+		"omitted year": {
+			Expected: Birthday{Day: 2, Month: time.November},
+			Field:    &vcard.Field{Value: "--1102"},
+		},
+		"omitted year extended": {
+			Expected: Birthday{Day: 2, Month: time.November},
+			Field:    &vcard.Field{Value: "--11-02"},
+		},
+		"date-time local": {
+			Expected: Birthday{Day: 5, Month: time.February, Year: 2000},
+			Field:    &vcard.Field{Value: "20000205T1030"},
+		},
+		"date-time utc": {
+			Expected: Birthday{Day: 5, Month: time.February, Year: 2000},
+			Field:    &vcard.Field{Value: "20000205T233000Z"},
+		},
+		"date-time offset": {
+			Expected: Birthday{Day: 5, Month: time.February, Year: 2000},
+			Field:    &vcard.Field{Value: "20000205T000000+0100"},
+		},
+		"date-time omitted year": {
+			Expected: Birthday{Day: 2, Month: time.November},
+			Field:    &vcard.Field{Value: "--1102T12"},
+		},
+		"iso with time (google)": {
+			Expected: Birthday{Day: 5, Month: time.February, Year: 2000},
+			Field:    &vcard.Field{Value: "2000-02-05T00:00:00.000Z"},
+		},
+		"iso with time (outlook)": {
+			Expected: Birthday{Day: 5, Month: time.February, Year: 2000},
+			Field:    &vcard.Field{Value: "2000-02-05T00:00:00"},
+		},
+		"iso with offset": {
+			Expected: Birthday{Day: 5, Month: time.February, Year: 2000},
+			Field:    &vcard.Field{Value: "2000-02-05T08:00:00-05:00"},
+		},
+		"year and month": {
+			Field:          &vcard.Field{Value: "2000-02"},
+			NotSchedulable: true,
+		},
+		"year only": {
+			Field:          &vcard.Field{Value: "2000"},
+			NotSchedulable: true,
+		},
+		"month only": {
+			Field:          &vcard.Field{Value: "--02"},
+			NotSchedulable: true,
+		},
+		"day only": {
+			Field:          &vcard.Field{Value: "---05"},
+			NotSchedulable: true,
+		},
+		"text value": {
+			Field: &vcard.Field{
+				Value:  "circa 1800",
+				Params: vcard.Params{"VALUE": []string{"text"}},
+			},
+			NotSchedulable: true,
+		},
+		"invalid time": {
+			Field:     &vcard.Field{Value: "20000205Tnoon"},
+			ExpectErr: true,
+		},
+		"garbage": {
+			Field:     &vcard.Field{Value: "circa 1800"},
+			ExpectErr: true,
+		},
 	} {
-		d, err := Parse(tc.Field)
-		require.NoError(t, err, tc.Field)
-		assert.Equal(t, tc.ExpectedTime, d)
+		t.Run(name, func(t *testing.T) {
+			d, err := Parse(tc.Field)
+
+			switch {
+			case tc.NotSchedulable:
+				assert.ErrorIs(t, err, ErrNotSchedulable)
+
+			case tc.ExpectErr:
+				assert.Error(t, err)
+				assert.NotErrorIs(t, err, ErrNotSchedulable)
+
+			default:
+				assert.NoError(t, err)
+				assert.Equal(t, tc.Expected, d)
+			}
+		})
 	}
 }
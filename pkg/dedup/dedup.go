@@ -0,0 +1,215 @@
+// Package dedup contains the logic to detect the same person being
+// present multiple times in the fetched address books and to reduce
+// them to a single entry
+package dedup
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/emersion/go-vcard"
+	"github.com/sirupsen/logrus"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/config"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/dateutil"
+)
+
+// Policies to handle duplicates
+const (
+	// PolicyKeepAll keeps all duplicates (notifying multiple times)
+	PolicyKeepAll = "keepAll"
+	// PolicyMerge keeps the preferred entry and adds the properties
+	// only present in the other entries to it
+	PolicyMerge = "merge"
+	// PolicyPrefer keeps the entry from the first matching preferred
+	// address book (or the first entry) and drops the others
+	PolicyPrefer = "prefer"
+)
+
+type (
+	// Deduplicator reduces duplicate entries according to its policy
+	Deduplicator struct {
+		policy    string
+		preferred []string
+	}
+
+	// Entry is a contact with its parsed birthday
+	Entry struct {
+		Account     string
+		AddressBook string
+		Birthday    dateutil.Birthday
+		Card        vcard.Card
+	}
+)
+
+// New validates the configuration and creates a new Deduplicator
+func New(cfg config.DeduplicateConfig) (*Deduplicator, error) {
+	switch cfg.Policy {
+	case PolicyKeepAll, PolicyMerge, PolicyPrefer:
+		// Fine
+
+	default:
+		return nil, fmt.Errorf("unknown policy %q, use %q, %q or %q", cfg.Policy, PolicyKeepAll, PolicyMerge, PolicyPrefer)
+	}
+
+	return &Deduplicator{policy: cfg.Policy, preferred: cfg.PreferAddressBooks}, nil
+}
+
+// Deduplicate groups the entries describing the same person (same UID
+// or same normalized name and birthday) and reduces each group to one
+// entry according to the policy. The order of the entries is kept.
+func (d *Deduplicator) Deduplicate(entries []Entry) []Entry {
+	if d.policy == PolicyKeepAll {
+		return entries
+	}
+
+	result := make([]Entry, 0, len(entries))
+
+	for _, group := range GroupEntries(entries) {
+		if len(group) == 1 {
+			result = append(result, entries[group[0]])
+			continue
+		}
+
+		keep := d.preferredEntry(entries, group)
+		kept := entries[keep]
+
+		var dropped []string
+		for _, idx := range group {
+			if idx == keep {
+				continue
+			}
+
+			dropped = append(dropped, entries[idx].source())
+			if d.policy == PolicyMerge {
+				kept.Card = mergeCards(kept.Card, entries[idx].Card)
+			}
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"dropped": dropped,
+			"kept":    kept.source(),
+			"name":    kept.Card.PreferredValue(vcard.FieldFormattedName),
+			"policy":  d.policy,
+		}).Info("merged duplicate contacts")
+
+		result = append(result, kept)
+	}
+
+	return result
+}
+
+// preferredEntry returns the index of the entry to keep: The first
+// entry in the first preferred address book or the first entry
+func (d *Deduplicator) preferredEntry(entries []Entry, group []int) int {
+	for _, book := range d.preferred {
+		for _, idx := range group {
+			if strings.EqualFold(entries[idx].AddressBook, book) {
+				return idx
+			}
+		}
+	}
+
+	return group[0]
+}
+
+// GroupEntries returns groups of indexes of the entries describing the
+// same person (same UID or same normalized name and birthday) ordered
+// by their first entry. Entries are grouped
+// transitively: If A shares the UID with B and B shares name and
+// birthday with C all three are in the same group.
+func GroupEntries(entries []Entry) (groups [][]int) {
+	// Union-find over the entries, the root of each set is its entry
+	// with the lowest index
+	parent := make([]int, len(entries))
+	for idx := range parent {
+		parent[idx] = idx
+	}
+
+	find := func(idx int) int {
+		for parent[idx] != idx {
+			parent[idx] = parent[parent[idx]]
+			idx = parent[idx]
+		}
+		return idx
+	}
+
+	firstByKey := make(map[string]int)
+	for idx, e := range entries {
+		for _, key := range entryKeys(e) {
+			first, ok := firstByKey[key]
+			if !ok {
+				firstByKey[key] = idx
+				continue
+			}
+
+			if a, b := find(first), find(idx); a != b {
+				parent[max(a, b)] = min(a, b)
+			}
+		}
+	}
+
+	groupByRoot := make(map[int]int)
+	for idx := range entries {
+		root := find(idx)
+
+		group, ok := groupByRoot[root]
+		if !ok {
+			group = len(groups)
+			groupByRoot[root] = group
+			groups = append(groups, nil)
+		}
+
+		groups[group] = append(groups[group], idx)
+	}
+
+	return groups
+}
+
+// entryKeys returns the keys identifying the person: The UID and the
+// normalized name combined with the birthday
+func entryKeys(e Entry) (keys []string) {
+	if uid := e.Card.Value(vcard.FieldUID); uid != "" {
+		keys = append(keys, "uid:"+uid)
+	}
+
+	if name := normalizeName(e.Card); name != "" {
+		keys = append(keys, "name:"+name+"|"+e.Birthday.String())
+	}
+
+	return keys
+}
+
+// source returns the account and address book the entry was found in
+func (e Entry) source() string {
+	return e.Account + "/" + e.AddressBook
+}
+
+// mergeCards returns a copy of base having the properties only present
+// in other added to it
+func mergeCards(base, other vcard.Card) vcard.Card {
+	merged := make(vcard.Card, len(base))
+	for k, v := range base {
+		merged[k] = v
+	}
+
+	for k, v := range other {
+		if _, ok := merged[k]; !ok {
+			merged[k] = slices.Clone(v)
+		}
+	}
+
+	return merged
+}
+
+// normalizeName returns the lower-cased formatted name (or given and
+// family name) with collapsed whitespace
+func normalizeName(card vcard.Card) string {
+	name := card.PreferredValue(vcard.FieldFormattedName)
+	if name == "" && card.Name() != nil {
+		name = card.Name().GivenName + " " + card.Name().FamilyName
+	}
+
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
@@ -0,0 +1,101 @@
+package dedup
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-vcard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/config"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/dateutil"
+)
+
+func getTestEntry(t *testing.T, addressBook, content string) Entry {
+	t.Helper()
+
+	c, err := vcard.NewDecoder(strings.NewReader("BEGIN:VCARD\nVERSION:4.0\n" + content + "END:VCARD")).Decode()
+	require.NoError(t, err)
+
+	return Entry{
+		AddressBook: addressBook,
+		Birthday:    dateutil.Birthday{Day: 13, Month: time.March, Year: 1990},
+		Card:        c,
+	}
+}
+
+func getTestEntries(t *testing.T) []Entry {
+	t.Helper()
+
+	return []Entry{
+		getTestEntry(t, "Personal", "FN:Joe Bloggs\nUID:joe\n"),
+		getTestEntry(t, "Personal", "FN:Jane Doe\n"),
+		getTestEntry(t, "Shared", "FN:Joe  Bloggs\nUID:joe\nEMAIL:joe@example.com\n"),
+		getTestEntry(t, "Shared", "FN:jane doe\nUID:jane\n"),
+		getTestEntry(t, "Shared", "FN:Max Mustermann\n"),
+	}
+}
+
+func TestDeduplicatePrefer(t *testing.T) {
+	d, err := New(config.DeduplicateConfig{Policy: PolicyPrefer, PreferAddressBooks: []string{"shared"}})
+	require.NoError(t, err)
+
+	result := d.Deduplicate(getTestEntries(t))
+	require.Len(t, result, 3)
+
+	for _, e := range result {
+		assert.Equal(t, "Shared", e.AddressBook)
+	}
+}
+
+func TestDeduplicateMerge(t *testing.T) {
+	d, err := New(config.DeduplicateConfig{Policy: PolicyMerge})
+	require.NoError(t, err)
+
+	entries := getTestEntries(t)
+	result := d.Deduplicate(entries)
+	require.Len(t, result, 3)
+
+	assert.Equal(t, "Personal", result[0].AddressBook)
+	assert.Equal(t, "joe@example.com", result[0].Card.Value(vcard.FieldEmail))
+	assert.Empty(t, entries[0].Card.Value(vcard.FieldEmail), "original card must not be modified")
+
+	assert.Equal(t, "Personal", result[1].AddressBook)
+	assert.Equal(t, "jane", result[1].Card.Value(vcard.FieldUID))
+}
+
+func TestDeduplicateTransitive(t *testing.T) {
+	d, err := New(config.DeduplicateConfig{Policy: PolicyMerge})
+	require.NoError(t, err)
+
+	// The first and the third entry share the UID, the third and the
+	// second entry share name and birthday: All of them are the same
+	// person even though the first two entries have nothing in common
+	entries := []Entry{
+		getTestEntry(t, "Personal", "FN:Joe Bloggs\nUID:joe\n"),
+		getTestEntry(t, "Work", "FN:Joseph Bloggs\nUID:joseph\nEMAIL:joe@example.com\n"),
+		getTestEntry(t, "Shared", "FN:Joseph Bloggs\nUID:joe\n"),
+		getTestEntry(t, "Shared", "FN:Max Mustermann\n"),
+	}
+
+	assert.Equal(t, [][]int{{0, 1, 2}, {3}}, GroupEntries(entries))
+
+	result := d.Deduplicate(entries)
+	require.Len(t, result, 2)
+	assert.Equal(t, "Personal", result[0].AddressBook)
+	assert.Equal(t, "joe@example.com", result[0].Card.Value(vcard.FieldEmail))
+}
+
+func TestDeduplicateKeepAll(t *testing.T) {
+	d, err := New(config.DeduplicateConfig{Policy: PolicyKeepAll})
+	require.NoError(t, err)
+
+	assert.Len(t, d.Deduplicate(getTestEntries(t)), 5)
+}
+
+func TestNewInvalidPolicy(t *testing.T) {
+	_, err := New(config.DeduplicateConfig{Policy: "random"})
+	assert.Error(t, err)
+}
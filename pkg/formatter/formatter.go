@@ -5,86 +5,274 @@ package formatter
 import (
 	"bytes"
 	"fmt"
-	"regexp"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"text/template"
 	"time"
 
-	"github.com/emersion/go-vcard"
-
-	"git.luzifer.io/luzifer/birthday-notifier/pkg/dateutil"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/i18n"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/milestone"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/notifier"
 )
 
-const timeDay = 24 * time.Hour
+const (
+	templateFileExt = ".tpl"
+	timeDay         = 24 * time.Hour
+)
 
 var (
-	// DefaultTemplate contains the template used in testing and as a
-	// default in the config package
-	DefaultTemplate = regexp.MustCompile(`\s+`).ReplaceAllString(strings.TrimSpace(strings.ReplaceAll(`
-{{ .contact | getName }} has their birthday {{ if .when | isToday -}} today {{- else -}} on {{ (.when | projectToNext).Format "Mon, 02 Jan" }} {{- end }}.
-{{ if gt .when.Year 1 -}}They are turning {{ .when | getAge }}.{{- end }}
-`, "\n", " ")), " ")
-
-	notifyTpl *template.Template
+	// DefaultTemplate contains the default template of the default
+	// locale, used in testing
+	DefaultTemplate = i18n.Lookup(i18n.DefaultLocale).DefaultTemplate
+
+	appendNotes  []string
+	localeTpls   map[string]*template.Template
+	milestoneTpl *template.Template
+	milestones   *milestone.Milestones
+	notifyTpl    *template.Template
+	tplSet       *template.Template
 )
 
-// FormatNotificationText takes the notification template and renders
-// the contact / birthday date into a text to submit in the notification
-func FormatNotificationText(contact vcard.Card, when time.Time) (text string, err error) {
-	buf := new(bytes.Buffer)
+// FormatNotificationText takes the notification template (or the
+// milestone template for milestone birthdays if configured) and renders
+// the event into a text to submit in the notification. Without a
+// configured template the default template of the locale of the event
+// is used. For notifications in advance the notes configured through
+// SetAppendNotes are appended to the text.
+func FormatNotificationText(evt notifier.Event) (text string, err error) {
+	tpl, err := notificationTemplate(evt)
+	if err != nil {
+		return "", err
+	}
 
-	if err = notifyTpl.Execute(buf, map[string]any{
-		"contact": contact,
-		"when":    when,
-	}); err != nil {
-		return "", fmt.Errorf("executing template: %w", err)
+	if text, err = RenderTemplate(tpl, evt); err != nil {
+		return "", err
 	}
 
-	return buf.String(), nil
+	if evt.Type() != notifier.EventTypeAdvance {
+		return text, nil
+	}
+
+	var notes []string
+	for _, property := range appendNotes {
+		notes = append(notes, getFields(property, evt.Contact)...)
+	}
+
+	if len(notes) == 0 {
+		return text, nil
+	}
+
+	return text + "\n\n" + strings.Join(notes, "\n"), nil
 }
 
 // FormatNotificationTitle provides a title from the contacts formatted
-// name or from given and family name
-func FormatNotificationTitle(contact vcard.Card) (title string) {
-	for _, fn := range contact.FormattedNames() {
-		if fn.Value != "" {
-			title = fmt.Sprintf("%s (Birthday)", fn.Value)
-		}
+// name or from given and family name in the locale of the event
+func FormatNotificationTitle(evt notifier.Event) (title string) {
+	suffix := i18n.Lookup(evt.Locale).Messages.Birthday
+
+	name := fullName(evt.Contact)
+	if name == "" {
+		return suffix
+	}
+
+	return fmt.Sprintf("%s (%s)", name, suffix)
+}
+
+// SetAppendNotes configures the vCard properties (i.e. NOTE or
+// X-GIFT-IDEAS) whose values are appended to the text of notifications
+// in advance of the birthday
+func SetAppendNotes(properties []string) {
+	appendNotes = properties
+}
+
+// SetMilestones configures the milestones used in the `isMilestone`
+// template function and the template to use for milestone birthdays.
+// If rawTpl is empty the notification template is used for them.
+func SetMilestones(m *milestone.Milestones, rawTpl string) (err error) {
+	milestones, milestoneTpl = m, nil
+
+	if rawTpl == "" {
+		return nil
 	}
 
-	if title == "" {
-		title = fmt.Sprintf("%s %s (Birthday)", contact.Name().GivenName, contact.Name().FamilyName)
+	if milestoneTpl, err = ParseTemplate("milestone", rawTpl); err != nil {
+		return fmt.Errorf("parsing milestone template: %w", err)
 	}
 
-	return title
+	return nil
 }
 
 // SetTemplate initializes the template to use in the
-// FormatNotificationText function. This MUST be called before first
+// FormatNotificationText function. If rawTpl is empty the default
+// templates of the locales are used. This MUST be called before first
 // use of the FormatNotificationText function.
-func SetTemplate(rawTpl string) error {
-	var err error
-	notifyTpl, err = template.New("notification").Funcs(template.FuncMap{
-		"getAge":        getAge,
-		"getName":       getContactName,
-		"isToday":       dateutil.IsToday,
-		"projectToNext": dateutil.ProjectToNextBirthday,
-	}).Parse(rawTpl)
+func SetTemplate(rawTpl string) (err error) {
+	localeTpls, notifyTpl = map[string]*template.Template{}, nil
+
+	if rawTpl != "" {
+		if notifyTpl, err = ParseTemplate("notification", rawTpl); err != nil {
+			return fmt.Errorf("parsing notification template: %w", err)
+		}
+
+		return nil
+	}
+
+	for _, name := range i18n.Names() {
+		if localeTpls[name], err = ParseTemplate("notification-"+name, i18n.Lookup(name).DefaultTemplate); err != nil {
+			return fmt.Errorf("parsing default template of locale %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// SetTemplateDir loads all `*.tpl` files in the given directory as
+// named templates (the file name without extension being the name).
+// They can be selected by name for notifications and included as
+// partials (`{{ template "footer" . }}`) in all templates parsed
+// afterwards. An empty dir removes all named templates. This MUST be
+// called before parsing the other templates.
+func SetTemplateDir(dir string) error {
+	tplSet = nil
+
+	if dir == "" {
+		return nil
+	}
+
+	entries, err := os.ReadDir(dir)
 	if err != nil {
-		return fmt.Errorf("parsing notification template: %w", err)
+		return fmt.Errorf("reading template dir: %w", err)
 	}
 
+	set := template.New("").Funcs(funcMap())
+
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != templateFileExt {
+			continue
+		}
+
+		raw, err := os.ReadFile(filepath.Join(dir, e.Name())) //#nosec:G304 // Intended to load templates from given dir
+		if err != nil {
+			return fmt.Errorf("reading template %q: %w", e.Name(), err)
+		}
+
+		if _, err = set.New(strings.TrimSuffix(e.Name(), templateFileExt)).Parse(string(raw)); err != nil {
+			return fmt.Errorf("parsing template %q: %w", e.Name(), err)
+		}
+	}
+
+	tplSet = set
 	return nil
 }
 
-func getAge(t time.Time) int {
-	return dateutil.ProjectToNextBirthday(t).Year() - t.Year()
+// ParseTemplate parses the given template having the same functions
+// available as the notification template and the named templates
+// loaded through SetTemplateDir as partials
+func ParseTemplate(name, rawTpl string) (*template.Template, error) {
+	base := template.New(name).Funcs(funcMap())
+	if tplSet != nil {
+		var err error
+		if base, err = tplSet.Clone(); err != nil {
+			return nil, fmt.Errorf("cloning named templates: %w", err)
+		}
+		base = base.New(name)
+	}
+
+	tpl, err := base.Parse(rawTpl)
+	if err != nil {
+		return nil, fmt.Errorf("parsing template: %w", err)
+	}
+
+	return tpl, nil
 }
 
-func getContactName(contact vcard.Card) string {
-	if contact.Name() != nil && contact.Name().GivenName != "" {
-		return contact.Name().GivenName
+// TemplateNames returns the names of the templates loaded through
+// SetTemplateDir (including the ones defined within the files)
+func TemplateNames() (names []string) {
+	if tplSet == nil {
+		return nil
+	}
+
+	for _, t := range tplSet.Templates() {
+		if t.Name() != "" {
+			names = append(names, t.Name())
+		}
 	}
 
-	return contact.FormattedNames()[0].Value
+	slices.Sort(names)
+	return names
+}
+
+// notificationTemplate selects the template to render the notification
+// text for the event with
+func notificationTemplate(evt notifier.Event) (*template.Template, error) {
+	switch {
+	case evt.Template != "":
+		return lookupTemplate(evt.Template)
+
+	case evt.Milestone && milestoneTpl != nil:
+		return milestoneTpl, nil
+
+	case notifyTpl != nil:
+		return notifyTpl, nil
+
+	default:
+		return localeTpls[i18n.Lookup(evt.Locale).Name], nil
+	}
+}
+
+// lookupTemplate returns the named template loaded through
+// SetTemplateDir
+func lookupTemplate(name string) (*template.Template, error) {
+	var tpl *template.Template
+	if tplSet != nil {
+		tpl = tplSet.Lookup(name)
+	}
+
+	if tpl == nil {
+		return nil, fmt.Errorf("template %q not found", name)
+	}
+
+	return tpl, nil
+}
+
+// RenderTemplate executes the given template with the same data as
+// the notification template:
+//
+//   - `account`: the label of the account the contact was fetched from
+//   - `actions`: the links to acknowledge / snooze the notification
+//     (`.actions.acknowledge`, `.actions.snooze`), empty if disabled
+//   - `contact`: the vcard.Card of the contact
+//   - `date`: the occurrence of the birthday the notification is about
+//   - `locale`: the i18n.Locale of the event (`.locale.Date .date`
+//     formats a date, `.locale.Plural <n> <one> <other>` pluralizes)
+//   - `milestone`: whether the contact reaches a milestone age
+//   - `when`: the dateutil.Birthday of the contact (`.when.HasYear`
+//     tells whether the year of birth is known)
+func RenderTemplate(tpl *template.Template, evt notifier.Event) (_ string, err error) {
+	defer func() {
+		// Functions panicking within the template must not take down
+		// the caller (i.e. the goroutine sending the notification)
+		if r := recover(); r != nil {
+			err = fmt.Errorf("executing template: panic: %v", r)
+		}
+	}()
+
+	buf := new(bytes.Buffer)
+
+	if err = tpl.Execute(buf, map[string]any{
+		"account":   evt.Account,
+		"actions":   evt.Actions,
+		"contact":   evt.Contact,
+		"date":      evt.Date,
+		"locale":    i18n.Lookup(evt.Locale),
+		"milestone": evt.Milestone,
+		"when":      evt.Birthday,
+	}); err != nil {
+		return "", fmt.Errorf("executing template: %w", err)
+	}
+
+	return buf.String(), nil
 }
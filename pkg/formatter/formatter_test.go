@@ -2,6 +2,8 @@ package formatter
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -11,6 +13,9 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"git.luzifer.io/luzifer/birthday-notifier/pkg/dateutil"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/i18n"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/milestone"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/notifier"
 )
 
 func getTestVCard(t *testing.T, content string) vcard.Card {
@@ -38,13 +43,16 @@ X-SOCIALPROFILE;TYPE=home;PREF=1:twitter:https://twitter.com/joebloggs
 END:VCARD`)
 
 	bday := time.Date(time.Now().Year()-30, time.Now().Month(), time.Now().Day(), 0, 0, 0, 0, time.Local)
+	evt := func(bday time.Time) notifier.Event {
+		return notifier.Event{Birthday: dateutil.NewBirthday(bday), Contact: card, Date: dateutil.ProjectToNextBirthday(bday)}
+	}
 
-	txt, err := FormatNotificationText(card, bday)
+	txt, err := FormatNotificationText(evt(bday))
 	require.NoError(t, err)
 	assert.Equal(t, "Joe has their birthday today. They are turning 30.", txt)
 
 	bday = bday.Add(timeDay)
-	txt, err = FormatNotificationText(card, bday)
+	txt, err = FormatNotificationText(evt(bday))
 	require.NoError(t, err)
 	assert.Equal(t, fmt.Sprintf(
 		"Joe has their birthday on %s. They are turning 30.",
@@ -52,10 +60,214 @@ END:VCARD`)
 	), txt)
 
 	bday = bday.Add(-2 * timeDay)
-	txt, err = FormatNotificationText(card, bday)
+	txt, err = FormatNotificationText(evt(bday))
 	require.NoError(t, err)
 	assert.Equal(t, fmt.Sprintf(
 		"Joe has their birthday on %s. They are turning 31.",
 		dateutil.ProjectToNextBirthday(time.Now().Add(-timeDay)).Format("Mon, 02 Jan"),
 	), txt)
+
+	// Notification after the birthday
+	txt, err = FormatNotificationText(notifier.Event{Birthday: dateutil.NewBirthday(bday), Contact: card, Date: dateutil.ProjectToPreviousBirthday(bday)})
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf(
+		"Joe had their birthday on %s. They turned 30.",
+		time.Now().Add(-timeDay).Format("Mon, 02 Jan"),
+	), txt)
+
+	// Unknown year
+	bday = time.Date(1, time.Now().Month(), time.Now().Day(), 0, 0, 0, 0, time.Local)
+	txt, err = FormatNotificationText(evt(bday))
+	require.NoError(t, err)
+	assert.Equal(t, "Joe has their birthday today.", txt)
+}
+
+func TestUnknownYear(t *testing.T) {
+	card := vcard.Card{}
+	card.SetName(&vcard.Name{GivenName: "Joe"})
+
+	evt := notifier.Event{
+		Birthday: dateutil.Birthday{Day: 13, Month: time.March},
+		Contact:  card,
+		Date:     time.Date(2026, 3, 13, 0, 0, 0, 0, time.Local),
+	}
+
+	for _, rawTpl := range []string{
+		`{{ .when | getAge }}`,
+		`{{ getAgeAt .when .date }}`,
+	} {
+		tpl, err := ParseTemplate("test", rawTpl)
+		require.NoError(t, err)
+
+		_, err = RenderTemplate(tpl, evt)
+		assert.ErrorIs(t, err, dateutil.ErrYearUnknown, rawTpl)
+	}
+
+	tpl, err := ParseTemplate("test", `{{ if .when.HasYear }}known{{ else }}{{ .when.Format "02.01." }}{{ end }}`)
+	require.NoError(t, err)
+	txt, err := RenderTemplate(tpl, evt)
+	require.NoError(t, err)
+	assert.Equal(t, "13.03.", txt)
+}
+
+func TestMilestoneTemplate(t *testing.T) {
+	require.NoError(t, SetTemplate(DefaultTemplate))
+
+	m, err := milestone.New(nil, 10, nil)
+	require.NoError(t, err)
+	require.NoError(t, SetMilestones(m, `{{ .contact | getName }} turns {{ getAgeAt .when .date }}!`))
+	defer func() { require.NoError(t, SetMilestones(nil, "")) }()
+
+	card := vcard.Card{}
+	card.SetName(&vcard.Name{GivenName: "Joe"})
+
+	var (
+		bday = dateutil.Birthday{Day: 13, Month: time.March, Year: 1996}
+		date = time.Date(2026, 3, 13, 0, 0, 0, 0, time.Local)
+	)
+
+	txt, err := FormatNotificationText(notifier.Event{Birthday: bday, Contact: card, Date: date, Milestone: true})
+	require.NoError(t, err)
+	assert.Equal(t, "Joe turns 30!", txt)
+
+	tpl, err := ParseTemplate("test", `{{ if isMilestone 30 }}yes{{ end }}/{{ if isMilestone 31 }}yes{{ end }}`)
+	require.NoError(t, err)
+	txt, err = RenderTemplate(tpl, notifier.Event{Birthday: bday, Contact: card, Date: date})
+	require.NoError(t, err)
+	assert.Equal(t, "yes/", txt)
+}
+
+func TestLocaleDefaultTemplates(t *testing.T) {
+	require.NoError(t, SetTemplate(""))
+	defer func() { require.NoError(t, SetTemplate(DefaultTemplate)) }()
+
+	card := vcard.Card{}
+	card.SetName(&vcard.Name{GivenName: "Joe"})
+
+	var (
+		date = dateutil.TodayStartOfDay().AddDate(0, 0, 3)
+		evt  = notifier.Event{
+			Birthday: dateutil.Birthday{Day: date.Day(), Month: date.Month(), Year: date.Year() - 1},
+			Contact:  card,
+			Date:     date,
+		}
+	)
+
+	for locale, expected := range map[string]string{
+		"":   "Joe has their birthday on %s. They are turning 1.",
+		"de": "Joe hat am %s Geburtstag und wird 1 Jahr alt.",
+		"en": "Joe has their birthday on %s. They are turning 1.",
+		"es": "Joe cumple años el %s (1 año).",
+		"fr": "Joe fête son anniversaire le %s (1 an).",
+	} {
+		evt.Locale = locale
+		txt, err := FormatNotificationText(evt)
+		require.NoError(t, err, locale)
+		assert.Equal(t, fmt.Sprintf(expected, i18n.Lookup(locale).Date(date)), txt, locale)
+	}
+
+	// Notification after the birthday
+	evt.Date = dateutil.TodayStartOfDay().AddDate(0, 0, -1)
+	evt.Birthday = dateutil.NewBirthday(evt.Date.AddDate(-30, 0, 0))
+	evt.Locale = "de"
+	txt, err := FormatNotificationText(evt)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("Joe hatte am %s Geburtstag und ist 30 Jahre alt geworden.", i18n.Lookup("de").Date(evt.Date)), txt)
+
+	evt.Date = dateutil.TodayStartOfDay()
+	evt.Birthday = dateutil.NewBirthday(evt.Date.AddDate(-30, 0, 0))
+	evt.Locale = "fr"
+	txt, err = FormatNotificationText(evt)
+	require.NoError(t, err)
+	assert.Equal(t, "Joe fête son anniversaire aujourd'hui (30 ans).", txt)
+
+	assert.Equal(t, "Joe (Anniversaire)", FormatNotificationTitle(notifier.Event{Contact: getTestVCard(t, "BEGIN:VCARD\nVERSION:4.0\nFN:Joe\nEND:VCARD"), Locale: "fr"}))
+}
+
+func TestTemplateDir(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"footer.tpl":   `{{ define "signature" }}-- {{ .locale.Name }}{{ end }}Don't forget the gift!`,
+		"reminder.tpl": `{{ .contact | getName }}: {{ template "footer" . }} {{ template "signature" . }}`,
+		"ignored.txt":  `{{ .invalid`,
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+
+	require.NoError(t, SetTemplateDir(dir))
+	defer func() {
+		require.NoError(t, SetTemplateDir(""))
+		require.NoError(t, SetTemplate(DefaultTemplate))
+	}()
+
+	assert.Equal(t, []string{"footer", "reminder", "signature"}, TemplateNames())
+
+	card := vcard.Card{}
+	card.SetName(&vcard.Name{GivenName: "Joe"})
+	evt := notifier.Event{Contact: card, Date: dateutil.TodayStartOfDay(), Template: "reminder"}
+
+	txt, err := FormatNotificationText(evt)
+	require.NoError(t, err)
+	assert.Equal(t, "Joe: Don't forget the gift! -- en", txt)
+
+	// Partials are available in inline templates
+	require.NoError(t, SetTemplate(`{{ template "signature" . }}`))
+	evt.Template = ""
+	txt, err = FormatNotificationText(evt)
+	require.NoError(t, err)
+	assert.Equal(t, "-- en", txt)
+
+	evt.Template = "missing"
+	_, err = FormatNotificationText(evt)
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.tpl"), []byte(`{{ .invalid`), 0o600))
+	assert.Error(t, SetTemplateDir(dir))
+}
+
+func TestFormatNotificationTitle(t *testing.T) {
+	withN := vcard.Card{}
+	withN.SetName(&vcard.Name{GivenName: "Joe", FamilyName: "Bloggs"})
+
+	for expected, card := range map[string]vcard.Card{
+		"Joe Bloggs (Birthday)": withN,
+		"Joe (Birthday)":        getTestVCard(t, "BEGIN:VCARD\nVERSION:4.0\nFN:Joe\nEND:VCARD"),
+		"Birthday":              {},
+	} {
+		assert.Equal(t, expected, FormatNotificationTitle(notifier.Event{Contact: card}))
+	}
+}
+
+func TestAppendNotes(t *testing.T) {
+	require.NoError(t, SetTemplate(`{{ .contact | getName }}`))
+	SetAppendNotes([]string{"NOTE", "X-GIFT-IDEAS"})
+	defer func() {
+		require.NoError(t, SetTemplate(DefaultTemplate))
+		SetAppendNotes(nil)
+	}()
+
+	card := getTestVCard(t, `BEGIN:VCARD
+VERSION:4.0
+FN:Joe
+NOTE:Likes jazz
+X-GIFT-IDEAS:Vinyl
+X-GIFT-IDEAS:Concert tickets
+END:VCARD`)
+
+	evt := notifier.Event{Contact: card, Date: dateutil.TodayStartOfDay().AddDate(0, 0, 3)}
+	txt, err := FormatNotificationText(evt)
+	require.NoError(t, err)
+	assert.Equal(t, "Joe\n\nLikes jazz\nVinyl\nConcert tickets", txt)
+
+	// Only notifications in advance get the notes
+	evt.Date = dateutil.TodayStartOfDay()
+	txt, err = FormatNotificationText(evt)
+	require.NoError(t, err)
+	assert.Equal(t, "Joe", txt)
+
+	// Contacts without notes are not modified
+	evt = notifier.Event{Contact: getTestVCard(t, "BEGIN:VCARD\nVERSION:4.0\nFN:Jane\nEND:VCARD"), Date: dateutil.TodayStartOfDay().AddDate(0, 0, 3)}
+	txt, err = FormatNotificationText(evt)
+	require.NoError(t, err)
+	assert.Equal(t, "Jane", txt)
 }
@@ -0,0 +1,324 @@
+package formatter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/emersion/go-vcard"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/dateutil"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/i18n"
+)
+
+type (
+	// FuncDoc documents a function available in the templates
+	FuncDoc struct {
+		Name        string
+		Usage       string
+		Description string
+	}
+
+	templateFunc struct {
+		FuncDoc
+		fn any
+	}
+)
+
+const (
+	decimalBase = 10
+
+	// maxRepeatLength limits the result of the `repeat` function to keep
+	// templates from producing huge notifications
+	maxRepeatLength = 4096
+)
+
+// Pronoun forms accepted by the `pronoun` template function
+const (
+	pronounObject     = "object"
+	pronounPossessive = "possessive"
+	pronounSubject    = "subject"
+)
+
+var (
+	ordinalSuffixes = []string{"th", "st", "nd", "rd"}
+
+	pronouns = map[vcard.Sex]map[string]string{
+		vcard.SexFemale: {pronounObject: "her", pronounPossessive: "her", pronounSubject: "she"},
+		vcard.SexMale:   {pronounObject: "him", pronounPossessive: "his", pronounSubject: "he"},
+	}
+	pronounsNeutral = map[string]string{pronounObject: "them", pronounPossessive: "their", pronounSubject: "they"}
+)
+
+// templateFuncs contains all functions available in the templates
+// together with their documentation. Functions taking a value to
+// operate on expect it as last argument so they can be used in pipes.
+var templateFuncs = []templateFunc{
+	// Birthday & date functions
+	{FuncDoc{"daysUntil", "daysUntil <date|birthday>", "Days until the date / next occurrence of the birthday (negative for past dates)"}, daysUntil},
+	{FuncDoc{"getAge", "getAge <birthday>", "Age the contact is turning at their next birthday (fails if the year is unknown)"}, getAge},
+	{FuncDoc{"getAgeAt", "getAgeAt <birthday> <date>", "Age the contact has at the given date (fails if the year is unknown)"}, getAgeAt},
+	{FuncDoc{"isMilestone", "isMilestone <age>", "Whether the age is a configured milestone"}, isMilestone},
+	{FuncDoc{"isPast", "isPast <date>", "Whether the date is before today"}, isPast},
+	{FuncDoc{"isToday", "isToday <date|birthday>", "Whether the date / birthday is today (dates are projected to their next anniversary)"}, isToday},
+	{FuncDoc{"ordinal", "ordinal <number>", "Number with English ordinal suffix (1st, 2nd, 30th)"}, ordinal},
+	{FuncDoc{"projectToNext", "projectToNext <birthday>", "Next occurrence of the birthday"}, projectToNext},
+	{FuncDoc{"weekday", "weekday <locale> <date>", "Name of the weekday in the given locale (de, en, es, fr)"}, weekday},
+
+	// Contact functions
+	{FuncDoc{"email", "email <contact>", "Preferred email address of the contact"}, email},
+	{FuncDoc{"fullName", "fullName <contact>", "Formatted name of the contact (falls back to given and family name)"}, fullName},
+	{FuncDoc{"getField", "getField <property> <contact>", "Preferred value of any vCard property of the contact (i.e. NOTE), empty if not present"}, getField},
+	{FuncDoc{"getFields", "getFields <property> <contact>", "All values of any vCard property of the contact (i.e. X-GIFT-IDEAS)"}, getFields},
+	{FuncDoc{"getName", "getName <contact>", "Given name of the contact (falls back to the formatted name)"}, getContactName},
+	{FuncDoc{"nickname", "nickname <contact>", "Nickname of the contact (falls back to getName)"}, nickname},
+	{FuncDoc{"organization", "organization <contact>", "Organization of the contact"}, organization},
+	{FuncDoc{"phone", "phone <contact>", "Preferred phone number of the contact"}, phone},
+	{FuncDoc{"pronoun", "pronoun <subject|object|possessive> <contact>", "Pronoun derived from the GENDER of the contact (falls back to they / them / their)"}, pronoun},
+
+	// String helpers
+	{FuncDoc{"contains", "contains <substr> <s>", "Whether s contains substr"}, func(substr, s string) bool { return strings.Contains(s, substr) }},
+	{FuncDoc{"default", "default <fallback> <value>", "Value or fallback if the value is empty"}, defaultValue},
+	{FuncDoc{"hasPrefix", "hasPrefix <prefix> <s>", "Whether s starts with prefix"}, func(prefix, s string) bool { return strings.HasPrefix(s, prefix) }},
+	{FuncDoc{"hasSuffix", "hasSuffix <suffix> <s>", "Whether s ends with suffix"}, func(suffix, s string) bool { return strings.HasSuffix(s, suffix) }},
+	{FuncDoc{"join", "join <sep> <list>", "Joins the list of strings using sep"}, func(sep string, l []string) string { return strings.Join(l, sep) }},
+	{FuncDoc{"lower", "lower <s>", "Lower-cased string"}, strings.ToLower},
+	{FuncDoc{"repeat", "repeat <count> <s>", "String repeated count times (result limited to 4096 bytes)"}, repeat},
+	{FuncDoc{"replace", "replace <old> <new> <s>", "Replaces all occurrences of old by new"}, func(o, n, s string) string { return strings.ReplaceAll(s, o, n) }},
+	{FuncDoc{"split", "split <sep> <s>", "Splits s into a list of strings at sep"}, func(sep, s string) []string { return strings.Split(s, sep) }},
+	{FuncDoc{"title", "title <s>", "Upper-cases the first letter of each word"}, title},
+	{FuncDoc{"trim", "trim <s>", "String without leading and trailing whitespace"}, strings.TrimSpace},
+	{FuncDoc{"trimPrefix", "trimPrefix <prefix> <s>", "String without the given prefix"}, func(prefix, s string) string { return strings.TrimPrefix(s, prefix) }},
+	{FuncDoc{"trimSuffix", "trimSuffix <suffix> <s>", "String without the given suffix"}, func(suffix, s string) string { return strings.TrimSuffix(s, suffix) }},
+	{FuncDoc{"truncate", "truncate <length> <s>", "String truncated to length characters (adding … if truncated)"}, truncate},
+	{FuncDoc{"upper", "upper <s>", "Upper-cased string"}, strings.ToUpper},
+}
+
+// TemplateFuncs returns the documentation of all functions available
+// in the templates
+func TemplateFuncs() []FuncDoc {
+	docs := make([]FuncDoc, 0, len(templateFuncs))
+	for _, f := range templateFuncs {
+		docs = append(docs, f.FuncDoc)
+	}
+
+	return docs
+}
+
+func funcMap() template.FuncMap {
+	fm := make(template.FuncMap, len(templateFuncs))
+	for _, f := range templateFuncs {
+		fm[f.Name] = f.fn
+	}
+
+	return fm
+}
+
+// daysUntil returns the days until the given date or the next
+// occurrence of the given birthday
+func daysUntil(v any) (int, error) {
+	switch t := v.(type) {
+	case dateutil.Birthday:
+		return dateutil.DaysUntil(t.NextOccurrence()), nil
+	case time.Time:
+		return dateutil.DaysUntil(t), nil
+	default:
+		return 0, fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+func defaultValue(fallback string, value any) string {
+	if value == nil {
+		return fallback
+	}
+
+	if s := fmt.Sprint(value); s != "" {
+		return s
+	}
+
+	return fallback
+}
+
+func email(contact vcard.Card) string {
+	return contact.PreferredValue(vcard.FieldEmail)
+}
+
+func fullName(contact vcard.Card) string {
+	if fn := contact.PreferredValue(vcard.FieldFormattedName); fn != "" {
+		return fn
+	}
+
+	if n := contact.Name(); n != nil {
+		return strings.TrimSpace(strings.Join([]string{n.GivenName, n.FamilyName}, " "))
+	}
+
+	return ""
+}
+
+// getAge returns the age the contact is turning at their next
+// birthday and fails the rendering if the year of birth is unknown
+func getAge(birthday dateutil.Birthday) (int, error) {
+	return getAgeAt(birthday, birthday.NextOccurrence())
+}
+
+// getAgeAt returns the age the contact has at the given date and fails
+// the rendering if the year of birth is unknown
+func getAgeAt(birthday dateutil.Birthday, date time.Time) (int, error) {
+	age, ok := birthday.Age(date)
+	if !ok {
+		return 0, dateutil.ErrYearUnknown
+	}
+
+	return age, nil
+}
+
+// getField returns the preferred value of the property given by name
+// (case-insensitive)
+func getField(name string, contact vcard.Card) string {
+	return contact.PreferredValue(strings.ToUpper(name))
+}
+
+// getFields returns all non-empty values of the property given by name
+// (case-insensitive)
+func getFields(name string, contact vcard.Card) (values []string) {
+	for _, v := range contact.Values(strings.ToUpper(name)) {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
+
+func getContactName(contact vcard.Card) string {
+	if n := contact.Name(); n != nil && n.GivenName != "" {
+		return n.GivenName
+	}
+
+	return contact.PreferredValue(vcard.FieldFormattedName)
+}
+
+// isMilestone checks whether the given age is a configured milestone
+func isMilestone(age int) bool {
+	return milestones.IsMilestone(age)
+}
+
+// isPast checks whether the given date is before today
+func isPast(t time.Time) bool {
+	return t.Before(dateutil.TodayStartOfDay())
+}
+
+// isToday checks whether the given date or the given birthday is
+// today. Dates are projected to their next anniversary.
+func isToday(v any) (bool, error) {
+	switch t := v.(type) {
+	case dateutil.Birthday:
+		return t.IsToday(), nil
+	case time.Time:
+		return dateutil.IsToday(t), nil
+	default:
+		return false, fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+func nickname(contact vcard.Card) string {
+	if nick := contact.PreferredValue(vcard.FieldNickname); nick != "" {
+		return nick
+	}
+
+	return getContactName(contact)
+}
+
+// ordinal returns the number with its English ordinal suffix
+func ordinal(n int) string {
+	abs := n
+	if abs < 0 {
+		abs = -abs
+	}
+
+	var (
+		lastDigit  = abs % decimalBase
+		tensDigit  = abs / decimalBase % decimalBase
+		suffix     = "th"
+		isTeenLike = tensDigit == 1 // 11th, 12th, 13th
+	)
+
+	if lastDigit < len(ordinalSuffixes) && !isTeenLike {
+		suffix = ordinalSuffixes[lastDigit]
+	}
+
+	return strconv.Itoa(n) + suffix
+}
+
+func organization(contact vcard.Card) string {
+	org := contact.PreferredValue(vcard.FieldOrganization)
+	name, _, _ := strings.Cut(org, ";")
+	return name
+}
+
+func phone(contact vcard.Card) string {
+	return contact.PreferredValue(vcard.FieldTelephone)
+}
+
+// pronoun returns the pronoun in the given form for the contact based
+// on the sex given in the GENDER field
+func pronoun(form string, contact vcard.Card) (string, error) {
+	if _, ok := pronounsNeutral[form]; !ok {
+		return "", fmt.Errorf("unknown pronoun form %q", form)
+	}
+
+	sex, _ := contact.Gender()
+	if p, ok := pronouns[sex]; ok {
+		return p[form], nil
+	}
+
+	return pronounsNeutral[form], nil
+}
+
+// projectToNext returns the next occurrence of the birthday
+func projectToNext(birthday dateutil.Birthday) time.Time {
+	return birthday.NextOccurrence()
+}
+
+func repeat(count int, s string) (string, error) {
+	if count <= 0 || s == "" {
+		return "", nil
+	}
+
+	if count > maxRepeatLength/len(s) {
+		return "", fmt.Errorf("result exceeds %d bytes", maxRepeatLength)
+	}
+
+	return strings.Repeat(s, count), nil
+}
+
+func title(s string) string {
+	words := strings.Fields(s)
+	for i, w := range words {
+		r := []rune(w)
+		words[i] = strings.ToUpper(string(r[0])) + string(r[1:])
+	}
+
+	return strings.Join(words, " ")
+}
+
+func truncate(length int, s string) string {
+	r := []rune(s)
+	if length < 0 || len(r) <= length {
+		return s
+	}
+
+	return string(r[:length]) + "…"
+}
+
+// weekday returns the localized name of the weekday of the date
+func weekday(locale string, t time.Time) (string, error) {
+	l, err := i18n.Get(locale)
+	if err != nil {
+		return "", fmt.Errorf("getting locale: %w", err)
+	}
+
+	return l.Weekdays[t.Weekday()], nil
+}
@@ -0,0 +1,172 @@
+package formatter
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-vcard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/dateutil"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/notifier"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	card := getTestVCard(t, `BEGIN:VCARD
+VERSION:4.0
+N:Bloggs;Joe;;;
+FN:Joe Bloggs
+NICKNAME:Joey
+ORG:ACME Inc.;Sales
+EMAIL:joe@example.com
+TEL:+49 123 456
+GENDER:M
+NOTE:Likes jazz
+X-GIFT-IDEAS:Vinyl
+X-GIFT-IDEAS:Concert tickets
+END:VCARD`)
+
+	evt := notifier.Event{
+		Birthday: dateutil.Birthday{Day: 13, Month: time.March, Year: 1996},
+		Contact:  card,
+		Date:     time.Date(2026, 3, 13, 0, 0, 0, 0, time.Local), // Friday
+	}
+
+	for tpl, expected := range map[string]string{
+		// Birthday & date functions
+		`{{ daysUntil .date | printf "%T" }}`:  "int",
+		`{{ weekday "de" .date }}`:             "Freitag",
+		`{{ .date | weekday "fr" }}`:           "vendredi",
+		`{{ getAgeAt .when .date | ordinal }}`: "30th",
+
+		// Contact functions
+		`{{ .contact | email }}`:                                "joe@example.com",
+		`{{ .contact | fullName }}`:                             "Joe Bloggs",
+		`{{ .contact | getField "note" }}`:                      "Likes jazz",
+		`{{ .contact | getField "X-MISSING" }}`:                 "",
+		`{{ .contact | getFields "X-GIFT-IDEAS" | join ", " }}`: "Vinyl, Concert tickets",
+		`{{ .contact | getFields "X-MISSING" | len }}`:          "0",
+		`{{ .contact | nickname }}`:                             "Joey",
+		`{{ .contact | organization }}`:                         "ACME Inc.",
+		`{{ .contact | phone }}`:                                "+49 123 456",
+		`{{ .contact | pronoun "subject" }}`:                    "he",
+		`{{ .contact | pronoun "possessive" }}`:                 "his",
+
+		// String helpers
+		`{{ "Hello" | contains "ell" }}`:       "true",
+		`{{ "" | default "none" }}`:            "none",
+		`{{ "x" | default "none" }}`:           "x",
+		`{{ "Hello" | hasPrefix "He" }}`:       "true",
+		`{{ "Hello" | hasSuffix "lo" }}`:       "true",
+		`{{ "a,b,c" | split "," | join "-" }}`: "a-b-c",
+		`{{ "Hello" | lower }}`:                "hello",
+		`{{ "ab" | repeat 3 }}`:                "ababab",
+		`{{ "Hello" | replace "l" "L" }}`:      "HeLLo",
+		`{{ "happy  birthday" | title }}`:      "Happy Birthday",
+		`{{ "  Hello  " | trim }}`:             "Hello",
+		`{{ "Hello" | trimPrefix "He" }}`:      "llo",
+		`{{ "Hello" | trimSuffix "lo" }}`:      "Hel",
+		`{{ "Hello World" | truncate 5 }}`:     "Hello…",
+		`{{ "Hello" | truncate 5 }}`:           "Hello",
+		`{{ "Hello" | upper }}`:                "HELLO",
+	} {
+		tmpl, err := ParseTemplate("test", tpl)
+		require.NoError(t, err, tpl)
+
+		txt, err := RenderTemplate(tmpl, evt)
+		require.NoError(t, err, tpl)
+		assert.Equal(t, expected, txt, tpl)
+	}
+}
+
+func TestOrdinal(t *testing.T) {
+	for n, expected := range map[int]string{
+		1: "1st", 2: "2nd", 3: "3rd", 4: "4th",
+		11: "11th", 12: "12th", 13: "13th",
+		21: "21st", 102: "102nd", 112: "112th",
+		-1: "-1st",
+	} {
+		assert.Equal(t, expected, ordinal(n))
+	}
+}
+
+func TestDaysUntil(t *testing.T) {
+	d, err := daysUntil(dateutil.TodayStartOfDay().AddDate(0, 0, 3))
+	require.NoError(t, err)
+	assert.Equal(t, 3, d)
+
+	d, err = daysUntil(dateutil.NewBirthday(time.Now().AddDate(-30, 0, 1)))
+	require.NoError(t, err)
+	assert.Equal(t, 1, d)
+
+	_, err = daysUntil("tomorrow")
+	assert.Error(t, err)
+}
+
+func TestPronounFallback(t *testing.T) {
+	for gender, expected := range map[string]string{
+		"F": "she",
+		"O": "they",
+		"":  "they",
+	} {
+		card := vcard.Card{}
+		if gender != "" {
+			card.SetValue(vcard.FieldGender, gender)
+		}
+
+		p, err := pronoun("subject", card)
+		require.NoError(t, err)
+		assert.Equal(t, expected, p, gender)
+	}
+
+	_, err := pronoun("reflexive", vcard.Card{})
+	assert.Error(t, err)
+}
+
+func TestRepeatLimit(t *testing.T) {
+	s, err := repeat(maxRepeatLength, "a")
+	require.NoError(t, err)
+	assert.Len(t, s, maxRepeatLength)
+
+	s, err = repeat(-1, "a")
+	require.NoError(t, err)
+	assert.Empty(t, s)
+
+	_, err = repeat(maxRepeatLength/2+1, "ab")
+	assert.Error(t, err)
+
+	_, err = repeat(math.MaxInt, "a")
+	assert.Error(t, err)
+}
+
+func TestWeekdayUnknownLocale(t *testing.T) {
+	_, err := weekday("xx", time.Now())
+	assert.Error(t, err)
+}
+
+func TestTemplateFuncsDocumented(t *testing.T) {
+	fm := funcMap()
+	docs := TemplateFuncs()
+
+	require.Len(t, docs, len(fm))
+	for _, d := range docs {
+		assert.Contains(t, fm, d.Name)
+		assert.NotEmpty(t, d.Description, d.Name)
+		assert.NotEmpty(t, d.Usage, d.Name)
+	}
+}
+
+func TestGetContactName(t *testing.T) {
+	withN := vcard.Card{}
+	withN.SetName(&vcard.Name{GivenName: "Joe", FamilyName: "Bloggs"})
+
+	for expected, card := range map[string]vcard.Card{
+		"Joe":        withN,
+		"Joe Bloggs": getTestVCard(t, "BEGIN:VCARD\nVERSION:4.0\nFN:Joe Bloggs\nEND:VCARD"),
+		"":           {},
+	} {
+		assert.Equal(t, expected, getContactName(card))
+	}
+}
@@ -0,0 +1,177 @@
+package formatter
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/emersion/go-vcard"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/dateutil"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/i18n"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/notifier"
+)
+
+const (
+	validationBirthYear = 1992
+	validationDaysAhead = 3
+)
+
+type (
+	// validationFixture is a synthetic event to render the templates
+	// against together with a human readable description of it
+	validationFixture struct {
+		desc string
+		evt  notifier.Event
+	}
+
+	validationBirthday struct {
+		desc     string
+		birthday dateutil.Birthday
+		dates    []time.Time
+	}
+
+	validationContact struct {
+		desc    string
+		contact vcard.Card
+	}
+)
+
+// ValidateNamedTemplate renders the template loaded through
+// SetTemplateDir against the validation fixtures (see ValidateTemplate)
+func ValidateNamedTemplate(name string, milestoneOnly bool) error {
+	tpl, err := lookupTemplate(name)
+	if err != nil {
+		return err
+	}
+
+	return ValidateTemplate(tpl, milestoneOnly)
+}
+
+// ValidateTemplate renders the template against synthetic edge-case
+// events (contacts with and without name, birthdays with and without
+// year and on Feb 29, notifications before, on and after the birthday,
+// all locales) to detect errors only happening at runtime (like using
+// getAge on a birthday without year). Templates used for milestones
+// only are rendered with events having a known year of birth.
+func ValidateTemplate(tpl *template.Template, milestoneOnly bool) error {
+	for _, f := range validationFixtures(milestoneOnly) {
+		if _, err := RenderTemplate(tpl, f.evt); err != nil {
+			return fmt.Errorf("rendering for %s: %w", f.desc, err)
+		}
+	}
+
+	return nil
+}
+
+// ValidateTemplates renders the notification template (or the default
+// templates of all locales) and the milestone template against the
+// validation fixtures (see ValidateTemplate)
+func ValidateTemplates() error {
+	if notifyTpl != nil {
+		if err := ValidateTemplate(notifyTpl, false); err != nil {
+			return fmt.Errorf("notification template: %w", err)
+		}
+	}
+
+	for name, tpl := range localeTpls {
+		if err := ValidateTemplate(tpl, false); err != nil {
+			return fmt.Errorf("default template of locale %q: %w", name, err)
+		}
+	}
+
+	if milestoneTpl != nil {
+		if err := ValidateTemplate(milestoneTpl, true); err != nil {
+			return fmt.Errorf("milestone template: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// validationFixtures creates all combinations of the edge-case
+// contacts, birthdays and dates in all locales
+func validationFixtures(milestoneOnly bool) (fixtures []validationFixture) {
+	for _, b := range validationBirthdays() {
+		for _, milestone := range []bool{true, false} {
+			if (milestoneOnly && !milestone) || (milestone && !b.birthday.HasYear()) {
+				// Milestones require a known year
+				continue
+			}
+
+			for _, c := range validationContacts() {
+				for _, date := range b.dates {
+					for _, locale := range i18n.Names() {
+						evt := notifier.Event{
+							Birthday:  b.birthday,
+							Contact:   c.contact,
+							Date:      date,
+							Locale:    locale,
+							Milestone: milestone,
+						}
+
+						desc := []string{c.desc, b.desc, evt.Type() + " notification", "locale " + locale}
+						if milestone {
+							desc = append(desc, "milestone")
+						}
+
+						fixtures = append(fixtures, validationFixture{desc: strings.Join(desc, ", "), evt: evt})
+					}
+				}
+			}
+		}
+	}
+
+	return fixtures
+}
+
+func validationBirthdays() []validationBirthday {
+	var (
+		today    = dateutil.TodayStartOfDay()
+		leapDay  = dateutil.Birthday{Day: 29, Month: time.February, Year: validationBirthYear}
+		birthday = func(date time.Time, year int) dateutil.Birthday {
+			return dateutil.Birthday{Day: date.Day(), Month: date.Month(), Year: year}
+		}
+	)
+
+	var birthdays []validationBirthday
+	for _, date := range []time.Time{today.AddDate(0, 0, validationDaysAhead), today, today.AddDate(0, 0, -1)} {
+		birthdays = append(
+			birthdays,
+			validationBirthday{"birthday with year", birthday(date, validationBirthYear), []time.Time{date}},
+			validationBirthday{"birthday without year", birthday(date, 0), []time.Time{date}},
+		)
+	}
+
+	return append(birthdays, validationBirthday{
+		"birthday on Feb 29",
+		leapDay,
+		[]time.Time{leapDay.NextOccurrence(), leapDay.PreviousOccurrence()},
+	})
+}
+
+func validationContacts() []validationContact {
+	full := vcard.Card{}
+	full.SetValue(vcard.FieldFormattedName, "Jane Doe")
+	full.SetName(&vcard.Name{GivenName: "Jane", FamilyName: "Doe"})
+	full.SetValue(vcard.FieldNickname, "Janie")
+	full.SetValue(vcard.FieldEmail, "jane@example.com")
+	full.SetValue(vcard.FieldTelephone, "+1 555 0100")
+	full.SetValue(vcard.FieldOrganization, "ACME Inc.")
+	full.SetValue(vcard.FieldGender, "F")
+	full.SetValue(vcard.FieldCategories, "friends")
+
+	fnOnly := vcard.Card{}
+	fnOnly.SetValue(vcard.FieldFormattedName, "Jane")
+
+	nameOnly := vcard.Card{}
+	nameOnly.SetName(&vcard.Name{GivenName: "Jane"})
+
+	return []validationContact{
+		{"contact with all fields", full},
+		{"contact with only FN", fnOnly},
+		{"contact with only N", nameOnly},
+		{"contact without name", vcard.Card{}},
+	}
+}
@@ -0,0 +1,79 @@
+package formatter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/dateutil"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/notifier"
+)
+
+func TestValidateTemplates(t *testing.T) {
+	defer func() {
+		require.NoError(t, SetTemplate(DefaultTemplate))
+		require.NoError(t, SetMilestones(nil, ""))
+	}()
+
+	require.NoError(t, SetTemplate(""))
+	require.NoError(t, SetMilestones(nil, ""))
+	assert.NoError(t, ValidateTemplates())
+
+	// Age is only known for birthdays having a year
+	require.NoError(t, SetTemplate(`{{ .contact | getName }} turns {{ .when | getAge }}`))
+	assert.ErrorIs(t, ValidateTemplates(), dateutil.ErrYearUnknown)
+
+	// ... which is fine for milestones
+	require.NoError(t, SetTemplate(DefaultTemplate))
+	require.NoError(t, SetMilestones(nil, `{{ .contact | getName }} turns {{ .when | getAge }}`))
+	assert.NoError(t, ValidateTemplates())
+}
+
+func TestValidateNamedTemplate(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "age.tpl"), []byte(`{{ getAgeAt .when .date }}`), 0o600))
+	require.NoError(t, SetTemplateDir(dir))
+	defer func() { require.NoError(t, SetTemplateDir("")) }()
+
+	assert.NoError(t, ValidateNamedTemplate("age", true))
+	assert.ErrorIs(t, ValidateNamedTemplate("age", false), dateutil.ErrYearUnknown)
+	assert.Error(t, ValidateNamedTemplate("missing", false))
+}
+
+func TestValidateTemplateFixtures(t *testing.T) {
+	for _, rawTpl := range []string{
+		`{{ .contact | getName }}`,
+		`{{ .contact | nickname }}`,
+		`{{ .contact | fullName }} {{ .locale.Date .date }}`,
+	} {
+		tpl, err := ParseTemplate("test", rawTpl)
+		require.NoError(t, err)
+		assert.NoError(t, ValidateTemplate(tpl, false), rawTpl)
+	}
+
+	tpl, err := ParseTemplate("test", `{{ (index .contact.N 0).Value }}`)
+	require.NoError(t, err)
+	err = ValidateTemplate(tpl, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "contact with only FN")
+
+	tpl, err = ParseTemplate("test", `{{ if and (eq .when.Month 2) (eq .when.Day 29) }}{{ index .contact.X 0 }}{{ end }}`)
+	require.NoError(t, err)
+	err = ValidateTemplate(tpl, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "birthday on Feb 29")
+}
+
+func TestRenderTemplatePanic(t *testing.T) {
+	tpl, err := template.New("test").Funcs(template.FuncMap{
+		"boom": func() string { panic("boom") },
+	}).Parse(`{{ boom }}`)
+	require.NoError(t, err)
+
+	_, err = RenderTemplate(tpl, notifier.Event{})
+	assert.ErrorContains(t, err, "boom")
+}
@@ -0,0 +1,158 @@
+package greeting
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"github.com/emersion/go-vcard"
+	"github.com/sirupsen/logrus"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/config"
+)
+
+const (
+	defaultEmailPort    = 587
+	defaultEmailSubject = "Happy birthday!"
+	implicitTLSPort     = 465
+	smtpTimeout         = 10 * time.Second
+)
+
+type (
+	emailSender struct {
+		cfg  config.GreetingEmailConfig
+		from *mail.Address
+	}
+)
+
+func newEmailSender(cfg config.GreetingEmailConfig) (s *emailSender, err error) {
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("host is required")
+	}
+
+	if cfg.Port == 0 {
+		cfg.Port = defaultEmailPort
+	}
+
+	if cfg.Subject == "" {
+		cfg.Subject = defaultEmailSubject
+	}
+
+	s = &emailSender{cfg: cfg}
+	if s.from, err = mail.ParseAddress(cfg.From); err != nil {
+		return nil, fmt.Errorf("parsing from address: %w", err)
+	}
+
+	return s, nil
+}
+
+func (*emailSender) Name() string { return "email" }
+
+func (*emailSender) Recipient(contact vcard.Card) string {
+	return contact.PreferredValue(vcard.FieldEmail)
+}
+
+func (s *emailSender) Send(recipient, text string) (err error) {
+	to, err := mail.ParseAddress(recipient)
+	if err != nil {
+		return fmt.Errorf("parsing recipient: %w", err)
+	}
+
+	msg := new(bytes.Buffer)
+	fmt.Fprintf(msg, "From: %s\r\n", s.from.String())
+	fmt.Fprintf(msg, "To: %s\r\n", to.String())
+	fmt.Fprintf(msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", s.cfg.Subject))
+	fmt.Fprintf(msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprint(msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprint(msg, "Content-Type: text/plain; charset=utf-8\r\n")
+	fmt.Fprint(msg, "Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	qp := quotedprintable.NewWriter(msg)
+	if _, err = qp.Write([]byte(text)); err != nil {
+		return fmt.Errorf("encoding body: %w", err)
+	}
+	if err = qp.Close(); err != nil {
+		return fmt.Errorf("finalizing body: %w", err)
+	}
+
+	return s.sendMail(to.Address, msg.Bytes())
+}
+
+func (s *emailSender) sendMail(to string, msg []byte) (err error) {
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+
+	dialer := &net.Dialer{Deadline: time.Now().Add(smtpTimeout)}
+
+	var conn net.Conn
+	if s.cfg.Port == implicitTLSPort {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: s.cfg.Host, MinVersion: tls.VersionTLS12})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("connecting to smtp server: %w", err)
+	}
+
+	if err = conn.SetDeadline(time.Now().Add(smtpTimeout)); err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("setting deadline: %w", err)
+	}
+
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("creating smtp client: %w", err)
+	}
+	defer func() {
+		if err := client.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			logrus.WithError(err).Error("closing smtp connection (leaked fd)")
+		}
+	}()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(&tls.Config{ServerName: s.cfg.Host, MinVersion: tls.VersionTLS12}); err != nil {
+			return fmt.Errorf("starting tls: %w", err)
+		}
+	}
+
+	if s.cfg.User != "" {
+		if err = client.Auth(smtp.PlainAuth("", s.cfg.User, s.cfg.Pass, s.cfg.Host)); err != nil {
+			return fmt.Errorf("authenticating: %w", err)
+		}
+	}
+
+	if err = client.Mail(s.from.Address); err != nil {
+		return fmt.Errorf("setting sender: %w", err)
+	}
+
+	if err = client.Rcpt(to); err != nil {
+		return fmt.Errorf("setting recipient: %w", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("starting data: %w", err)
+	}
+
+	if _, err = w.Write(msg); err != nil {
+		return fmt.Errorf("writing message: %w", err)
+	}
+
+	if err = w.Close(); err != nil {
+		return fmt.Errorf("finishing message: %w", err)
+	}
+
+	if err = client.Quit(); err != nil {
+		return fmt.Errorf("quitting session: %w", err)
+	}
+
+	return nil
+}
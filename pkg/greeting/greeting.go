@@ -0,0 +1,171 @@
+// Package greeting contains the logic to send a congratulation to the
+// contact having their birthday through email or SMS
+package greeting
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/emersion/go-vcard"
+	"github.com/sirupsen/logrus"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/config"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/formatter"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/notifier"
+)
+
+const (
+	// DefaultOptInProperty is the vCard property checked to determine
+	// whether a contact wants to receive greetings
+	DefaultOptInProperty = "X-BIRTHDAY-GREETING"
+	// DefaultSendAt is the time of the day greetings are sent at when
+	// no time is configured
+	DefaultSendAt = "09:00"
+	// DefaultTemplate is the greeting sent when no template is
+	// configured
+	DefaultTemplate = `Happy birthday, {{ .contact | getName }}! 🎉`
+
+	// ModeDryRun only logs the greetings which would have been sent
+	ModeDryRun = "dry-run"
+	// ModeSend actually sends the greetings to the contacts
+	ModeSend = "send"
+)
+
+type (
+	// Greeter sends greetings to contacts which opted in to receive them
+	Greeter struct {
+		cfg     config.GreetingConfig
+		sendAt  time.Time
+		senders []sender
+		tpl     *template.Template
+	}
+
+	sender interface {
+		// Name returns the name of the channel used in logs
+		Name() string
+		// Recipient returns the address of the contact for the channel
+		// or an empty string if the contact cannot be reached
+		Recipient(contact vcard.Card) string
+		// Send delivers the text to the recipient
+		Send(recipient, text string) error
+	}
+)
+
+var optInValues = []string{"1", "on", "true", "yes"}
+
+// New validates the configuration and creates a new Greeter from it
+func New(cfg config.GreetingConfig) (g *Greeter, err error) {
+	switch cfg.Mode {
+	case ModeDryRun, ModeSend:
+		// Fine
+
+	case "":
+		return nil, fmt.Errorf("mode is mandatory, set to %q or %q", ModeDryRun, ModeSend)
+
+	default:
+		return nil, fmt.Errorf("unknown mode %q, use %q or %q", cfg.Mode, ModeDryRun, ModeSend)
+	}
+
+	if cfg.OptInProperty == "" {
+		cfg.OptInProperty = DefaultOptInProperty
+	}
+
+	if cfg.SendAt == "" {
+		cfg.SendAt = DefaultSendAt
+	}
+
+	if cfg.Template == "" {
+		cfg.Template = DefaultTemplate
+	}
+
+	g = &Greeter{cfg: cfg}
+
+	if g.sendAt, err = time.Parse("15:04", cfg.SendAt); err != nil {
+		return nil, fmt.Errorf("parsing sendAt %q (expected HH:MM): %w", cfg.SendAt, err)
+	}
+
+	if g.tpl, err = formatter.ParseTemplate("greeting", cfg.Template); err != nil {
+		return nil, fmt.Errorf("parsing template: %w", err)
+	}
+
+	if err = formatter.ValidateTemplate(g.tpl, false); err != nil {
+		return nil, fmt.Errorf("validating template: %w", err)
+	}
+
+	if cfg.Email != nil {
+		s, err := newEmailSender(*cfg.Email)
+		if err != nil {
+			return nil, fmt.Errorf("configuring email: %w", err)
+		}
+		g.senders = append(g.senders, s)
+	}
+
+	if cfg.SMS != nil {
+		s, err := newSMSSender(*cfg.SMS)
+		if err != nil {
+			return nil, fmt.Errorf("configuring sms: %w", err)
+		}
+		g.senders = append(g.senders, s)
+	}
+
+	if len(g.senders) == 0 {
+		return nil, fmt.Errorf("at least one of email and sms must be configured")
+	}
+
+	return g, nil
+}
+
+// SendTime returns the time to send the greeting at on the given day
+func (g *Greeter) SendTime(day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), g.sendAt.Hour(), g.sendAt.Minute(), 0, 0, day.Location())
+}
+
+// Greet renders the greeting and sends it through the first channel
+// the contact can be reached on
+func (g *Greeter) Greet(evt notifier.Event) error {
+	text, err := formatter.RenderTemplate(g.tpl, evt)
+	if err != nil {
+		return fmt.Errorf("rendering greeting: %w", err)
+	}
+
+	for _, s := range g.senders {
+		recipient := s.Recipient(evt.Contact)
+		if recipient == "" {
+			continue
+		}
+
+		logger := logrus.WithFields(logrus.Fields{
+			"channel":   s.Name(),
+			"recipient": recipient,
+		})
+
+		if g.cfg.Mode == ModeDryRun {
+			logger.WithField("text", text).Info("dry-run: would send greeting")
+			return nil
+		}
+
+		if err = s.Send(recipient, text); err != nil {
+			return fmt.Errorf("sending greeting through %s: %w", s.Name(), err)
+		}
+
+		logger.Info("greeting sent")
+		return nil
+	}
+
+	return fmt.Errorf("contact has no address for any configured channel")
+}
+
+// OptedIn checks whether the contact has the configured category or
+// the opt-in property set to a truthy value
+func (g *Greeter) OptedIn(contact vcard.Card) bool {
+	if g.cfg.OptInCategory != "" && slices.ContainsFunc(contact.Categories(), func(c string) bool {
+		return strings.EqualFold(c, g.cfg.OptInCategory)
+	}) {
+		return true
+	}
+
+	return slices.Contains(optInValues, strings.ToLower(strings.TrimSpace(contact.Value(g.cfg.OptInProperty))))
+}
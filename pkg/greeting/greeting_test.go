@@ -0,0 +1,112 @@
+package greeting
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-vcard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/config"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/dateutil"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/notifier"
+)
+
+func getTestVCard(t *testing.T, extra string) vcard.Card {
+	t.Helper()
+
+	c, err := vcard.NewDecoder(strings.NewReader("BEGIN:VCARD\nVERSION:4.0\nN:Bloggs;Joe;;;\nFN:Joe Bloggs\n" + extra + "END:VCARD")).Decode()
+	require.NoError(t, err)
+
+	return c
+}
+
+func TestNew(t *testing.T) {
+	sms := &config.GreetingSMSConfig{Webhook: "https://example.com/"}
+
+	_, err := New(config.GreetingConfig{SMS: sms})
+	assert.Error(t, err, "mode must be mandatory")
+
+	_, err = New(config.GreetingConfig{Mode: "yolo", SMS: sms})
+	assert.Error(t, err, "mode must be known")
+
+	_, err = New(config.GreetingConfig{Mode: ModeSend})
+	assert.Error(t, err, "channel must be configured")
+
+	_, err = New(config.GreetingConfig{Mode: ModeSend, Email: &config.GreetingEmailConfig{Host: "localhost", From: "invalid"}})
+	assert.Error(t, err, "from must be valid")
+
+	_, err = New(config.GreetingConfig{Mode: ModeDryRun, SendAt: "9am", SMS: sms})
+	assert.Error(t, err, "sendAt must be valid")
+
+	_, err = New(config.GreetingConfig{Mode: ModeDryRun, SMS: sms})
+	assert.NoError(t, err)
+}
+
+func TestSendTime(t *testing.T) {
+	day := time.Date(2026, 3, 13, 0, 0, 0, 0, time.Local)
+	sms := &config.GreetingSMSConfig{Webhook: "https://example.com/"}
+
+	g, err := New(config.GreetingConfig{Mode: ModeDryRun, SMS: sms})
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 13, 9, 0, 0, 0, time.Local), g.SendTime(day))
+
+	g, err = New(config.GreetingConfig{Mode: ModeDryRun, SendAt: "18:30", SMS: sms})
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 13, 18, 30, 0, 0, time.Local), g.SendTime(day))
+}
+
+func TestOptedIn(t *testing.T) {
+	g, err := New(config.GreetingConfig{
+		Mode:          ModeDryRun,
+		OptInCategory: "greet",
+		SMS:           &config.GreetingSMSConfig{Webhook: "https://example.com/"},
+	})
+	require.NoError(t, err)
+
+	assert.False(t, g.OptedIn(getTestVCard(t, "")))
+	assert.False(t, g.OptedIn(getTestVCard(t, "CATEGORIES:family,friends\n")))
+	assert.True(t, g.OptedIn(getTestVCard(t, "CATEGORIES:family,Greet\n")))
+	assert.True(t, g.OptedIn(getTestVCard(t, "X-BIRTHDAY-GREETING:yes\n")))
+	assert.False(t, g.OptedIn(getTestVCard(t, "X-BIRTHDAY-GREETING:no\n")))
+}
+
+func TestGreetSMS(t *testing.T) {
+	var received struct{ Text, To string }
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	cfg := config.GreetingConfig{
+		Template: "Happy birthday {{ .contact | getName }}",
+		SMS: &config.GreetingSMSConfig{
+			Headers: map[string]string{"Authorization": "Bearer secret"},
+			Webhook: srv.URL,
+		},
+	}
+	contact := getTestVCard(t, "TEL;TYPE=home:+49 30 1234\nTEL;TYPE=cell:tel:+49 171 1234-56\n")
+
+	cfg.Mode = ModeDryRun
+	g, err := New(cfg)
+	require.NoError(t, err)
+	require.NoError(t, g.Greet(notifier.Event{Birthday: dateutil.NewBirthday(time.Now()), Contact: contact, Date: time.Now()}))
+	assert.Empty(t, received.To, "dry-run must not send")
+
+	cfg.Mode = ModeSend
+	g, err = New(cfg)
+	require.NoError(t, err)
+	require.NoError(t, g.Greet(notifier.Event{Birthday: dateutil.NewBirthday(time.Now()), Contact: contact, Date: time.Now()}))
+	assert.Equal(t, "+49171123456", received.To)
+	assert.Equal(t, "Happy birthday Joe", received.Text)
+
+	assert.Error(t, g.Greet(notifier.Event{Birthday: dateutil.NewBirthday(time.Now()), Contact: getTestVCard(t, ""), Date: time.Now()}), "contact without number must fail")
+}
@@ -0,0 +1,100 @@
+package greeting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-vcard"
+	"github.com/sirupsen/logrus"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/config"
+)
+
+const webhookPostTimeout = 5 * time.Second
+
+type (
+	smsSender struct {
+		cfg config.GreetingSMSConfig
+	}
+)
+
+func newSMSSender(cfg config.GreetingSMSConfig) (*smsSender, error) {
+	if cfg.Webhook == "" {
+		return nil, fmt.Errorf("webhook is required")
+	}
+
+	return &smsSender{cfg: cfg}, nil
+}
+
+func (*smsSender) Name() string { return "sms" }
+
+// Recipient prefers a number marked as cell and falls back to the
+// preferred number of the contact
+func (*smsSender) Recipient(contact vcard.Card) string {
+	for _, f := range contact[vcard.FieldTelephone] {
+		if f.Params.HasType(vcard.TypeCell) {
+			return normalizeNumber(f.Value)
+		}
+	}
+
+	return normalizeNumber(contact.PreferredValue(vcard.FieldTelephone))
+}
+
+func (s *smsSender) Send(recipient, text string) error {
+	payload := new(bytes.Buffer)
+	if err := json.NewEncoder(payload).Encode(struct {
+		Text string `json:"text"`
+		To   string `json:"to"`
+	}{
+		Text: text,
+		To:   recipient,
+	}); err != nil {
+		return fmt.Errorf("encoding payload: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookPostTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.Webhook, payload)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("executing request: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logrus.WithError(err).Error("closing sms gateway response body (leaked fd)")
+		}
+	}()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// normalizeNumber strips the `tel:` URI prefix used in vCard 4 and
+// any formatting characters from the number
+func normalizeNumber(number string) string {
+	number = strings.TrimPrefix(number, "tel:")
+
+	return strings.Map(func(r rune) rune {
+		if (r >= '0' && r <= '9') || r == '+' {
+			return r
+		}
+		return -1
+	}, number)
+}
@@ -0,0 +1,204 @@
+// Package i18n contains the locale packs used to translate the
+// notification texts and to format dates with localized month and
+// weekday names
+package i18n
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+)
+
+// DefaultLocale is the locale used when no locale is configured
+const DefaultLocale = "en"
+
+type (
+	// Locale contains the translations and date names for one language
+	Locale struct {
+		// Name is the name of the locale used in the configuration
+		Name string
+		// Tag is the language the locale is written in, used to select
+		// the plural forms
+		Tag language.Tag
+
+		// DateLayout is the Go layout used to format dates in texts
+		DateLayout string
+		// DefaultTemplate is the notification template used when no
+		// template is configured
+		DefaultTemplate string
+		// Messages contains the texts used outside of templates
+		Messages Messages
+
+		// Months, ShortMonths, Weekdays and ShortWeekdays contain the
+		// names used in dates as defined in CLDR: golang.org/x/text only
+		// uses them internally and provides no API to retrieve them
+		Months        [12]string
+		ShortMonths   [12]string
+		Weekdays      [7]string
+		ShortWeekdays [7]string
+	}
+
+	// Messages contains the texts used by the notifiers outside of
+	// the notification template
+	Messages struct {
+		Acknowledge string
+		Birthday    string
+		DaysAgo     Plural
+		InDays      Plural
+		Snooze      string
+		Today       string
+		Tomorrow    string
+		Turned      string
+		Turning     string
+		Yesterday   string
+	}
+
+	// Plural contains the singular and the plural form of a text. Both
+	// may contain a `%d` for the count.
+	Plural struct {
+		One   string
+		Other string
+	}
+)
+
+var (
+	locales = map[string]*Locale{}
+	matcher language.Matcher
+	names   []string
+)
+
+func init() {
+	var tags []language.Tag
+
+	for _, l := range []*Locale{localeDE, localeEN, localeES, localeFR} {
+		l.DefaultTemplate = collapseWhitespace(l.DefaultTemplate)
+		locales[l.Name] = l
+		names = append(names, l.Name)
+		tags = append(tags, l.Tag)
+	}
+
+	matcher = language.NewMatcher(tags)
+}
+
+// Get returns the locale best matching the given name (i.e. `de-AT`
+// yields the `de` locale). An empty name yields the DefaultLocale.
+func Get(name string) (*Locale, error) {
+	if name == "" {
+		return locales[DefaultLocale], nil
+	}
+
+	if l, ok := locales[strings.ToLower(name)]; ok {
+		return l, nil
+	}
+
+	tag, err := language.Parse(name)
+	if err != nil {
+		return nil, fmt.Errorf("parsing locale %q: %w", name, err)
+	}
+
+	_, idx, confidence := matcher.Match(tag)
+	if confidence == language.No {
+		return nil, fmt.Errorf("unsupported locale %q, use one of %s", name, strings.Join(names, ", "))
+	}
+
+	return locales[names[idx]], nil
+}
+
+// Lookup returns the locale for the given name like Get but falls
+// back to the DefaultLocale if the locale is not supported
+func Lookup(name string) *Locale {
+	l, err := Get(name)
+	if err != nil {
+		return locales[DefaultLocale]
+	}
+
+	return l
+}
+
+// Names returns the names of all supported locales
+func Names() []string {
+	return append([]string(nil), names...)
+}
+
+// Count returns the form of the text matching the count having the
+// count filled in
+func (l *Locale) Count(p Plural, n int) string {
+	return fmt.Sprintf(l.Plural(n, p.One, p.Other), n)
+}
+
+// Date formats the date using the DateLayout of the locale
+func (l *Locale) Date(t time.Time) string {
+	return l.FormatDate(l.DateLayout, t)
+}
+
+// FormatDate formats the date like time.Time.Format does but uses the
+// localized names for months (`January`, `Jan`) and weekdays
+// (`Monday`, `Mon`)
+func (l *Locale) FormatDate(layout string, t time.Time) string {
+	var buf strings.Builder
+
+	for layout != "" {
+		prefix, name, rest := l.nextNameChunk(layout, t)
+		if prefix != "" {
+			buf.WriteString(t.Format(prefix))
+		}
+		buf.WriteString(name)
+		layout = rest
+	}
+
+	return buf.String()
+}
+
+// Plural returns one or other depending on the plural form the count
+// has in the language of the locale. All forms except "one" (i.e.
+// "few" or "many" in some languages) yield other.
+func (l *Locale) Plural(n int, one, other string) string {
+	if n < 0 {
+		n = -n
+	}
+
+	if plural.Cardinal.MatchPlural(l.Tag, n, 0, 0, 0, 0) == plural.One {
+		return one
+	}
+
+	return other
+}
+
+// nextNameChunk splits the layout at the next month / weekday name
+// and returns the layout before it, the localized name and the rest of
+// the layout after it. The names are detected the same way the time
+// package does: `Jan` and `Mon` must not be followed by a lower-case
+// letter.
+func (l *Locale) nextNameChunk(layout string, t time.Time) (prefix, name, rest string) {
+	for i := 0; i < len(layout); i++ {
+		switch {
+		case strings.HasPrefix(layout[i:], "January"):
+			return layout[:i], l.Months[t.Month()-1], layout[i+len("January"):]
+
+		case strings.HasPrefix(layout[i:], "Jan") && !startsWithLower(layout[i+len("Jan"):]):
+			return layout[:i], l.ShortMonths[t.Month()-1], layout[i+len("Jan"):]
+
+		case strings.HasPrefix(layout[i:], "Monday"):
+			return layout[:i], l.Weekdays[t.Weekday()], layout[i+len("Monday"):]
+
+		case strings.HasPrefix(layout[i:], "Mon") && !startsWithLower(layout[i+len("Mon"):]):
+			return layout[:i], l.ShortWeekdays[t.Weekday()], layout[i+len("Mon"):]
+		}
+	}
+
+	return layout, "", ""
+}
+
+// collapseWhitespace allows to write the default templates on
+// multiple lines while rendering them into a single line
+func collapseWhitespace(tpl string) string {
+	return regexp.MustCompile(`\s+`).ReplaceAllString(strings.TrimSpace(tpl), " ")
+}
+
+func startsWithLower(s string) bool {
+	return s != "" && 'a' <= s[0] && s[0] <= 'z'
+}
@@ -0,0 +1,72 @@
+package i18n
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+	for name, expected := range map[string]string{
+		"":      DefaultLocale,
+		"de":    "de",
+		"DE":    "de",
+		"de-AT": "de",
+		"en-GB": "en",
+		"es-MX": "es",
+		"fr_CH": "fr",
+	} {
+		l, err := Get(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, expected, l.Name, name)
+	}
+
+	for _, name := range []string{"ja", "not a locale"} {
+		_, err := Get(name)
+		assert.Error(t, err, name)
+		assert.Equal(t, DefaultLocale, Lookup(name).Name, name)
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	date := time.Date(2026, 3, 13, 0, 0, 0, 0, time.UTC) // Friday
+
+	for name, expected := range map[string]string{
+		"de": "Freitag, 13. März",
+		"en": "Fri, 13 Mar",
+		"es": "viernes 13 de marzo",
+		"fr": "vendredi 13 mars",
+	} {
+		assert.Equal(t, expected, Lookup(name).Date(date), name)
+	}
+
+	l := Lookup("de")
+	assert.Equal(t, "Fr., 13. März 2026", l.FormatDate("Mon, 2. Jan 2006", date))
+	// `Month` is no month name in Go layouts
+	assert.Equal(t, "Month 03", l.FormatDate("Month 01", date))
+	assert.Equal(t, "", l.FormatDate("", date))
+}
+
+func TestPlural(t *testing.T) {
+	for _, tc := range []struct {
+		locale   string
+		n        int
+		expected string
+	}{
+		{"en", 0, "0 days ago"},
+		{"en", 1, "1 day ago"},
+		{"en", 2, "2 days ago"},
+		{"de", 1, "Vor 1 Tag"},
+		{"de", 3, "Vor 3 Tagen"},
+		// French uses the singular for zero
+		{"fr", 0, "Il y a 0 jour"},
+		{"fr", 2, "Il y a 2 jours"},
+	} {
+		l := Lookup(tc.locale)
+		assert.Equal(t, tc.expected, l.Count(l.Messages.DaysAgo, tc.n), tc.locale)
+	}
+
+	assert.Equal(t, "year", Lookup("en").Plural(-1, "year", "years"))
+}
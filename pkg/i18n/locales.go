@@ -0,0 +1,139 @@
+package i18n
+
+import "golang.org/x/text/language"
+
+var localeDE = &Locale{
+	Name: "de",
+	Tag:  language.German,
+
+	DateLayout: "Monday, 2. January",
+	DefaultTemplate: `
+{{ .contact | getName }}
+{{ if .date | isPast -}}
+  hatte am {{ .locale.Date .date }} Geburtstag
+  {{- if .when.HasYear }} und ist {{ $age := getAgeAt .when .date }}{{ $age }} {{ .locale.Plural $age "Jahr" "Jahre" }} alt geworden{{ end }}.
+{{- else -}}
+  hat {{ if .date | isToday -}} heute {{- else -}} am {{ .locale.Date .date }} {{- end }} Geburtstag
+  {{- if .when.HasYear }} und wird {{ $age := getAgeAt .when .date }}{{ $age }} {{ .locale.Plural $age "Jahr" "Jahre" }} alt{{ end }}.
+{{- end }}
+`,
+	Messages: Messages{
+		Acknowledge: "Erledigt",
+		Birthday:    "Geburtstag",
+		DaysAgo:     Plural{"Vor %d Tag", "Vor %d Tagen"},
+		InDays:      Plural{"In %d Tag", "In %d Tagen"},
+		Snooze:      "Später erinnern",
+		Today:       "Heute",
+		Tomorrow:    "Morgen",
+		Turned:      "Wurde",
+		Turning:     "Wird",
+		Yesterday:   "Gestern",
+	},
+
+	Months:        [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+	ShortMonths:   [12]string{"Jan.", "Feb.", "März", "Apr.", "Mai", "Juni", "Juli", "Aug.", "Sept.", "Okt.", "Nov.", "Dez."},
+	Weekdays:      [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+	ShortWeekdays: [7]string{"So.", "Mo.", "Di.", "Mi.", "Do.", "Fr.", "Sa."},
+}
+
+var localeEN = &Locale{
+	Name: "en",
+	Tag:  language.English,
+
+	DateLayout: "Mon, 02 Jan",
+	DefaultTemplate: `
+{{ .contact | getName }}
+{{ if .date | isPast -}}
+  had their birthday on {{ .locale.Date .date }}.
+  {{- if .when.HasYear }} They turned {{ getAgeAt .when .date }}.{{ end }}
+{{- else -}}
+  has their birthday {{ if .date | isToday -}} today {{- else -}} on {{ .locale.Date .date }} {{- end }}.
+  {{- if .when.HasYear }} They are turning {{ getAgeAt .when .date }}.{{ end }}
+{{- end }}
+`,
+	Messages: Messages{
+		Acknowledge: "Done",
+		Birthday:    "Birthday",
+		DaysAgo:     Plural{"%d day ago", "%d days ago"},
+		InDays:      Plural{"In %d day", "In %d days"},
+		Snooze:      "Remind me later",
+		Today:       "Today",
+		Tomorrow:    "Tomorrow",
+		Turned:      "Turned",
+		Turning:     "Turning",
+		Yesterday:   "Yesterday",
+	},
+
+	Months:        [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+	ShortMonths:   [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+	Weekdays:      [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+	ShortWeekdays: [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"},
+}
+
+var localeES = &Locale{
+	Name: "es",
+	Tag:  language.Spanish,
+
+	DateLayout: "Monday 2 de January",
+	DefaultTemplate: `
+{{ .contact | getName }}
+{{ if .date | isPast -}}
+  cumplió años el {{ .locale.Date .date }}
+  {{- if .when.HasYear }} ({{ $age := getAgeAt .when .date }}{{ $age }} {{ .locale.Plural $age "año" "años" }}){{ end }}.
+{{- else -}}
+  cumple años {{ if .date | isToday -}} hoy {{- else -}} el {{ .locale.Date .date }} {{- end }}
+  {{- if .when.HasYear }} ({{ $age := getAgeAt .when .date }}{{ $age }} {{ .locale.Plural $age "año" "años" }}){{ end }}.
+{{- end }}
+`,
+	Messages: Messages{
+		Acknowledge: "Hecho",
+		Birthday:    "Cumpleaños",
+		DaysAgo:     Plural{"Hace %d día", "Hace %d días"},
+		InDays:      Plural{"En %d día", "En %d días"},
+		Snooze:      "Recordar más tarde",
+		Today:       "Hoy",
+		Tomorrow:    "Mañana",
+		Turned:      "Cumplió",
+		Turning:     "Cumple",
+		Yesterday:   "Ayer",
+	},
+
+	Months:        [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+	ShortMonths:   [12]string{"ene", "feb", "mar", "abr", "may", "jun", "jul", "ago", "sept", "oct", "nov", "dic"},
+	Weekdays:      [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+	ShortWeekdays: [7]string{"dom", "lun", "mar", "mié", "jue", "vie", "sáb"},
+}
+
+var localeFR = &Locale{
+	Name: "fr",
+	Tag:  language.French,
+
+	DateLayout: "Monday 2 January",
+	DefaultTemplate: `
+{{ .contact | getName }}
+{{ if .date | isPast -}}
+  a fêté son anniversaire le {{ .locale.Date .date }}
+  {{- if .when.HasYear }} ({{ $age := getAgeAt .when .date }}{{ $age }} {{ .locale.Plural $age "an" "ans" }}){{ end }}.
+{{- else -}}
+  fête son anniversaire {{ if .date | isToday -}} aujourd'hui {{- else -}} le {{ .locale.Date .date }} {{- end }}
+  {{- if .when.HasYear }} ({{ $age := getAgeAt .when .date }}{{ $age }} {{ .locale.Plural $age "an" "ans" }}){{ end }}.
+{{- end }}
+`,
+	Messages: Messages{
+		Acknowledge: "Fait",
+		Birthday:    "Anniversaire",
+		DaysAgo:     Plural{"Il y a %d jour", "Il y a %d jours"},
+		InDays:      Plural{"Dans %d jour", "Dans %d jours"},
+		Snooze:      "Me le rappeler plus tard",
+		Today:       "Aujourd'hui",
+		Tomorrow:    "Demain",
+		Turned:      "A eu",
+		Turning:     "Va avoir",
+		Yesterday:   "Hier",
+	},
+
+	Months:        [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+	ShortMonths:   [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+	Weekdays:      [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+	ShortWeekdays: [7]string{"dim.", "lun.", "mar.", "mer.", "jeu.", "ven.", "sam."},
+}
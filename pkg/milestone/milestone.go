@@ -0,0 +1,75 @@
+// Package milestone contains the logic to detect milestone birthdays
+// (round birthdays like 18, 30, 40, …) which deserve additional
+// notifications
+package milestone
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/advance"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/dateutil"
+)
+
+type (
+	// Milestones decides which ages are milestones and which additional
+	// advance rules apply to them. A nil Milestones has no milestones.
+	Milestones struct {
+		advance []advance.Rule
+		ages    []int
+		every   int
+	}
+)
+
+// New validates the given ages and creates a new Milestones. An age is
+// a milestone if it is contained in ages or if every is non-zero and
+// the age is a multiple of it.
+func New(ages []int, every int, rules []advance.Rule) (*Milestones, error) {
+	if len(ages) == 0 && every == 0 {
+		return nil, fmt.Errorf("at least one of ages and every must be set")
+	}
+
+	if every < 0 {
+		return nil, fmt.Errorf("every must not be negative")
+	}
+
+	for _, age := range ages {
+		if age <= 0 {
+			return nil, fmt.Errorf("age %d is not a positive number", age)
+		}
+	}
+
+	return &Milestones{
+		advance: rules,
+		ages:    ages,
+		every:   every,
+	}, nil
+}
+
+// Advance returns the additional advance rules to apply to the given
+// occurrence of the birthday in case it is a milestone
+func (m *Milestones) Advance(birthday dateutil.Birthday, occurrence time.Time) []advance.Rule {
+	if !m.Reached(birthday, occurrence) {
+		return nil
+	}
+
+	return m.advance
+}
+
+// IsMilestone checks whether the given age is a milestone
+func (m *Milestones) IsMilestone(age int) bool {
+	if m == nil || age <= 0 {
+		return false
+	}
+
+	return slices.Contains(m.ages, age) || (m.every > 0 && age%m.every == 0)
+}
+
+// Reached checks whether the contact born at birthday reaches a
+// milestone age at the given occurrence of their birthday. Birthdays
+// without a known year never reach a milestone.
+func (m *Milestones) Reached(birthday dateutil.Birthday, occurrence time.Time) bool {
+	age, ok := birthday.Age(occurrence)
+	return ok && m.IsMilestone(age)
+}
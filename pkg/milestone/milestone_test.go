@@ -0,0 +1,58 @@
+package milestone
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/advance"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/dateutil"
+)
+
+func TestIsMilestone(t *testing.T) {
+	m, err := New([]int{18}, 10, nil)
+	require.NoError(t, err)
+
+	for age, expected := range map[int]bool{
+		0:  false,
+		18: true,
+		20: true,
+		25: false,
+		30: true,
+		31: false,
+	} {
+		assert.Equal(t, expected, m.IsMilestone(age), age)
+	}
+
+	var nilMilestones *Milestones
+	assert.False(t, nilMilestones.IsMilestone(30))
+}
+
+func TestAdvance(t *testing.T) {
+	rules := []advance.Rule{advance.MustParse("30"), advance.MustParse("14")}
+
+	m, err := New(nil, 10, rules)
+	require.NoError(t, err)
+
+	var (
+		birthday   = dateutil.Birthday{Day: 13, Month: time.March, Year: 1996}
+		occurrence = time.Date(2026, 3, 13, 0, 0, 0, 0, time.Local)
+	)
+
+	assert.Equal(t, rules, m.Advance(birthday, occurrence))
+	assert.Nil(t, m.Advance(birthday, occurrence.AddDate(1, 0, 0)))
+	assert.Nil(t, m.Advance(dateutil.Birthday{Day: 13, Month: time.March}, occurrence), "unknown year")
+}
+
+func TestNewValidation(t *testing.T) {
+	_, err := New(nil, 0, nil)
+	assert.Error(t, err)
+
+	_, err = New(nil, -10, nil)
+	assert.Error(t, err)
+
+	_, err = New([]int{0}, 0, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,300 @@
+// Package discord provides a notifier to send birthday notifications
+// as embeds through a native Discord WebHook
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Luzifer/go_helpers/fieldcollection"
+	"github.com/emersion/go-vcard"
+	"github.com/sirupsen/logrus"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/formatter"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/notifier"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/photo"
+)
+
+const (
+	defaultColor = 0xF1C40F
+
+	errorBodyExcerptLength = 1024
+
+	maxRateLimitRetries = 3
+	maxRetryAfter       = 30 * time.Second
+	photoFilename       = "photo.jpg"
+	webhookPostTimeout  = 2 * time.Second
+)
+
+type (
+	// Notifier implements the notifier interface
+	Notifier struct{}
+
+	attachment struct {
+		Filename string `json:"filename"`
+		ID       int    `json:"id"`
+	}
+
+	embed struct {
+		Color       int64           `json:"color"`
+		Description string          `json:"description"`
+		Thumbnail   *embedThumbnail `json:"thumbnail,omitempty"`
+		Timestamp   string          `json:"timestamp"`
+		Title       string          `json:"title"`
+	}
+
+	embedThumbnail struct {
+		URL string `json:"url"`
+	}
+
+	rateLimitResponse struct {
+		RetryAfter float64 `json:"retry_after"`
+	}
+)
+
+var (
+	ptrStrEmpty = func(v string) *string { return &v }("")
+
+	_ notifier.Notifier = Notifier{}
+)
+
+// SendNotification implements the Notifier interface
+func (Notifier) SendNotification(settings *fieldcollection.FieldCollection, evt notifier.Event) error {
+	if evt.Contact.Name() == nil {
+		return fmt.Errorf("contact has no name")
+	}
+
+	text, err := formatter.FormatNotificationText(evt)
+	if err != nil {
+		return fmt.Errorf("rendering text: %w", err)
+	}
+
+	color, err := parseColor(settings)
+	if err != nil {
+		return fmt.Errorf("parsing color: %w", err)
+	}
+
+	e := embed{
+		Color:       color,
+		Description: text,
+		Timestamp:   evt.Date.Format(time.RFC3339),
+		Title:       formatter.FormatNotificationTitle(evt),
+	}
+
+	var (
+		attachments []attachment
+		photoData   []byte
+	)
+
+	switch {
+	case evt.Photo != nil && len(evt.Photo.Data) > 0:
+		// Upload the resized photo and reference it from the embed
+		attachments = []attachment{{Filename: photoFilename}}
+		e.Thumbnail = &embedThumbnail{URL: "attachment://" + photoFilename}
+		photoData = evt.Photo.Data
+
+	case getPhotoURL(evt.Contact) != "":
+		e.Thumbnail = &embedThumbnail{URL: getPhotoURL(evt.Contact)}
+	}
+
+	payload, err := json.Marshal(struct {
+		Attachments []attachment `json:"attachments,omitempty"`
+		AvatarURL   string       `json:"avatar_url,omitempty"`
+		Embeds      []embed      `json:"embeds"`
+		Username    string       `json:"username,omitempty"`
+	}{
+		Attachments: attachments,
+		AvatarURL:   settings.MustString("avatarURL", ptrStrEmpty),
+		Embeds:      []embed{e},
+		Username:    settings.MustString("username", ptrStrEmpty),
+	})
+	if err != nil {
+		return fmt.Errorf("encoding hook payload: %w", err)
+	}
+
+	body, contentType, err := buildRequestBody(payload, photoData)
+	if err != nil {
+		return fmt.Errorf("building request body: %w", err)
+	}
+
+	for i := 0; ; i++ {
+		retryAfter, err := executeWebhook(settings.MustString("webhook", nil), body, contentType)
+		if err != nil {
+			return err
+		}
+
+		if retryAfter == 0 {
+			return nil
+		}
+
+		if i >= maxRateLimitRetries {
+			return fmt.Errorf("still rate-limited after %d retries", maxRateLimitRetries)
+		}
+
+		logrus.WithField("retry_after", retryAfter).Debug("discord webhook rate-limited, waiting")
+		time.Sleep(retryAfter)
+	}
+}
+
+// ValidateSettings implements the Notifier interface
+func (Notifier) ValidateSettings(settings *fieldcollection.FieldCollection) (err error) {
+	if v, err := settings.String("webhook"); err != nil || v == "" {
+		return fmt.Errorf("webhook is expected to be non-empty string")
+	}
+
+	if _, err = parseColor(settings); err != nil {
+		return fmt.Errorf("color is invalid: %w", err)
+	}
+
+	return nil
+}
+
+// executeWebhook posts the payload to the webhook and returns the
+// duration to wait before retrying in case the request was
+// rate-limited or zero in case it was successful
+func executeWebhook(webhook string, body []byte, contentType string) (retryAfter time.Duration, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookPostTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("executing request: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logrus.WithError(err).Error("closing discord response body (leaked fd)")
+		}
+	}()
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return parseRetryAfter(resp), nil
+
+	case resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices:
+		return 0, nil
+
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, errorBodyExcerptLength))
+		return 0, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+}
+
+// buildRequestBody returns the JSON payload as body or a multipart
+// body containing the payload and the photo if photoData is given
+func buildRequestBody(payload, photoData []byte) (body []byte, contentType string, err error) {
+	if len(photoData) == 0 {
+		return payload, "application/json", nil
+	}
+
+	buf := new(bytes.Buffer)
+	mw := multipart.NewWriter(buf)
+
+	pw, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Disposition": {`form-data; name="payload_json"`},
+		"Content-Type":        {"application/json"},
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("creating payload part: %w", err)
+	}
+
+	if _, err = pw.Write(payload); err != nil {
+		return nil, "", fmt.Errorf("writing payload part: %w", err)
+	}
+
+	fw, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Disposition": {fmt.Sprintf(`form-data; name="files[0]"; filename=%q`, photoFilename)},
+		"Content-Type":        {photo.ContentType},
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("creating photo part: %w", err)
+	}
+
+	if _, err = fw.Write(photoData); err != nil {
+		return nil, "", fmt.Errorf("writing photo part: %w", err)
+	}
+
+	if err = mw.Close(); err != nil {
+		return nil, "", fmt.Errorf("closing multipart writer: %w", err)
+	}
+
+	return buf.Bytes(), mw.FormDataContentType(), nil
+}
+
+// getPhotoURL returns the PHOTO of the contact in case it is given as
+// an URL. Inline photos cannot be referenced by Discord and are
+// therefore ignored.
+func getPhotoURL(contact vcard.Card) string {
+	for _, f := range contact[vcard.FieldPhoto] {
+		if strings.HasPrefix(f.Value, "https://") || strings.HasPrefix(f.Value, "http://") {
+			return f.Value
+		}
+	}
+
+	return ""
+}
+
+// parseColor reads the color setting as integer or as hex-string in
+// the form of `#rrggbb` and falls back to the default color
+func parseColor(settings *fieldcollection.FieldCollection) (int64, error) {
+	if !settings.HasAll("color") {
+		return defaultColor, nil
+	}
+
+	if settings.CanInt64("color") {
+		return settings.MustInt64("color", nil), nil
+	}
+
+	v, err := settings.String("color")
+	if err != nil {
+		return 0, fmt.Errorf("reading color: %w", err)
+	}
+
+	color, err := strconv.ParseInt(strings.TrimPrefix(v, "#"), 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("parsing hex color: %w", err)
+	}
+
+	return color, nil
+}
+
+// parseRetryAfter reads the time to wait from the Retry-After header
+// or from the JSON body of the response and caps it to a sane maximum
+func parseRetryAfter(resp *http.Response) time.Duration {
+	var seconds float64
+
+	if v, err := strconv.ParseFloat(resp.Header.Get("Retry-After"), 64); err == nil {
+		seconds = v
+	} else {
+		var body rateLimitResponse
+		if err = json.NewDecoder(resp.Body).Decode(&body); err == nil {
+			seconds = body.RetryAfter
+		}
+	}
+
+	retryAfter := time.Duration(seconds * float64(time.Second))
+	switch {
+	case retryAfter <= 0:
+		return time.Second
+
+	case retryAfter > maxRetryAfter:
+		return maxRetryAfter
+
+	default:
+		return retryAfter
+	}
+}
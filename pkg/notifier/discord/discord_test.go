@@ -0,0 +1,197 @@
+package discord
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Luzifer/go_helpers/fieldcollection"
+	"github.com/emersion/go-vcard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/dateutil"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/formatter"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/notifier"
+)
+
+func getTestEvent(t *testing.T) notifier.Event {
+	t.Helper()
+
+	c, err := vcard.NewDecoder(strings.NewReader(`BEGIN:VCARD
+VERSION:4.0
+N:Bloggs;Joe;;;
+FN:Joe Bloggs
+PHOTO:https://example.com/joe.jpg
+END:VCARD`)).Decode()
+	require.NoError(t, err)
+
+	bday := time.Date(time.Now().Year()-30, time.Now().Month(), time.Now().Day(), 0, 0, 0, 0, time.Local)
+	return notifier.Event{Birthday: dateutil.NewBirthday(bday), Contact: c, Date: dateutil.ProjectToNextBirthday(bday)}
+}
+
+func TestParseColor(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Settings map[string]any
+		Expected int64
+		Valid    bool
+	}{
+		"default":     {Settings: map[string]any{}, Expected: defaultColor, Valid: true},
+		"integer":     {Settings: map[string]any{"color": 3447003}, Expected: 3447003, Valid: true},
+		"hex":         {Settings: map[string]any{"color": "#3498db"}, Expected: 0x3498DB, Valid: true},
+		"hex no hash": {Settings: map[string]any{"color": "3498db"}, Expected: 0x3498DB, Valid: true},
+		"invalid hex": {Settings: map[string]any{"color": "#blue"}},
+		"wrong type":  {Settings: map[string]any{"color": []string{"#3498db"}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			color, err := parseColor(fieldcollection.FromData(tc.Settings))
+			if !tc.Valid {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.Expected, color)
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Header   string
+		Body     string
+		Expected time.Duration
+	}{
+		"header":       {Header: "1.5", Expected: 1500 * time.Millisecond},
+		"body":         {Body: `{"retry_after": 0.25}`, Expected: 250 * time.Millisecond},
+		"header first": {Header: "2", Body: `{"retry_after": 5}`, Expected: 2 * time.Second},
+		"capped":       {Header: "3600", Expected: maxRetryAfter},
+		"missing":      {Expected: time.Second},
+		"invalid body": {Body: "nope", Expected: time.Second},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}, Body: io.NopCloser(strings.NewReader(tc.Body))}
+			if tc.Header != "" {
+				resp.Header.Set("Retry-After", tc.Header)
+			}
+
+			assert.Equal(t, tc.Expected, parseRetryAfter(resp))
+		})
+	}
+}
+
+func TestBuildRequestBody(t *testing.T) {
+	payload := []byte(`{"embeds":[]}`)
+
+	body, contentType, err := buildRequestBody(payload, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", contentType)
+	assert.Equal(t, payload, body)
+
+	body, contentType, err = buildRequestBody(payload, []byte("jpeg-data"))
+	require.NoError(t, err)
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/form-data", mediaType)
+
+	mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+
+	part, err := mr.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "payload_json", part.FormName())
+	data, err := io.ReadAll(part)
+	require.NoError(t, err)
+	assert.Equal(t, payload, data)
+
+	part, err = mr.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "files[0]", part.FormName())
+	assert.Equal(t, photoFilename, part.FileName())
+	data, err = io.ReadAll(part)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("jpeg-data"), data)
+
+	_, err = mr.NextPart()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestSendNotification(t *testing.T) {
+	require.NoError(t, formatter.SetTemplate(formatter.DefaultTemplate))
+
+	var (
+		calls   atomic.Int32
+		payload struct {
+			Embeds []embed `json:"embeds"`
+		}
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			// Rate-limit the first request
+			w.Header().Set("Retry-After", "0.01")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	settings := fieldcollection.FromData(map[string]any{"webhook": srv.URL, "color": "#3498db"})
+	require.NoError(t, Notifier{}.ValidateSettings(settings))
+	require.NoError(t, Notifier{}.SendNotification(settings, getTestEvent(t)))
+
+	assert.Equal(t, int32(2), calls.Load())
+	require.Len(t, payload.Embeds, 1)
+	assert.Equal(t, int64(0x3498DB), payload.Embeds[0].Color)
+	assert.Contains(t, payload.Embeds[0].Description, "Joe")
+	require.NotNil(t, payload.Embeds[0].Thumbnail)
+	assert.Equal(t, "https://example.com/joe.jpg", payload.Embeds[0].Thumbnail.URL)
+}
+
+func TestSendNotificationRateLimited(t *testing.T) {
+	require.NoError(t, formatter.SetTemplate(formatter.DefaultTemplate))
+
+	var calls atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"retry_after": 0.01}`))
+	}))
+	defer srv.Close()
+
+	err := Notifier{}.SendNotification(fieldcollection.FromData(map[string]any{"webhook": srv.URL}), getTestEvent(t))
+	assert.ErrorContains(t, err, "rate-limited")
+	assert.Equal(t, int32(maxRateLimitRetries+1), calls.Load())
+}
+
+func TestSendNotificationError(t *testing.T) {
+	require.NoError(t, formatter.SetTemplate(formatter.DefaultTemplate))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "invalid webhook token", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	err := Notifier{}.SendNotification(fieldcollection.FromData(map[string]any{"webhook": srv.URL}), getTestEvent(t))
+	assert.ErrorContains(t, err, "unexpected status 401: invalid webhook token")
+}
+
+func TestValidateSettings(t *testing.T) {
+	assert.Error(t, Notifier{}.ValidateSettings(fieldcollection.FromData(map[string]any{})))
+	assert.Error(t, Notifier{}.ValidateSettings(fieldcollection.FromData(map[string]any{"webhook": "https://example.com", "color": "#nope"})))
+	assert.NoError(t, Notifier{}.ValidateSettings(fieldcollection.FromData(map[string]any{"webhook": "https://example.com"})))
+}
@@ -0,0 +1,227 @@
+// Package exec contains a notifier executing a local command for each
+// notification to integrate with anything available on the host
+package exec //revive:disable-line:package-naming // it's a package executing commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Luzifer/go_helpers/fieldcollection"
+	"github.com/emersion/go-vcard"
+	"github.com/sirupsen/logrus"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/dateutil"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/formatter"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/notifier"
+)
+
+const (
+	defaultTimeout       = 10 * time.Second
+	stderrExcerptLength  = 1024
+	terminationWaitDelay = time.Second
+)
+
+type (
+	// Notifier implements the notifier interface
+	Notifier struct{}
+
+	event struct {
+		Account       string            `json:"account,omitempty"`
+		Actions       map[string]string `json:"actions,omitempty"`
+		Age           *int              `json:"age,omitempty"`
+		Birthday      string            `json:"birthday"`
+		DaysInAdvance int               `json:"daysInAdvance"`
+		FormattedName string            `json:"formattedName"`
+		Milestone     bool              `json:"milestone"`
+		Occurrence    string            `json:"occurrence"`
+		Text          string            `json:"text"`
+		Title         string            `json:"title"`
+		UID           string            `json:"uid,omitempty"`
+		VCard         string            `json:"vcard"`
+	}
+)
+
+var (
+	ptrDurationDefault = func(v time.Duration) *time.Duration { return &v }(defaultTimeout)
+	ptrSliceEmpty      = func(v []string) *[]string { return &v }(nil)
+
+	_ notifier.Notifier = Notifier{}
+)
+
+// SendNotification implements the Notifier interface
+func (Notifier) SendNotification(settings *fieldcollection.FieldCollection, evt notifier.Event) error {
+	text, err := formatter.FormatNotificationText(evt)
+	if err != nil {
+		return fmt.Errorf("rendering text: %w", err)
+	}
+
+	args, err := renderArgs(settings.MustStringSlice("args", ptrSliceEmpty), evt)
+	if err != nil {
+		return fmt.Errorf("rendering args: %w", err)
+	}
+
+	payload, err := newEvent(evt, text)
+	if err != nil {
+		return fmt.Errorf("creating event: %w", err)
+	}
+
+	stdin, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), settings.MustDuration("timeout", ptrDurationDefault))
+	defer cancel()
+
+	var (
+		stderr = new(bytes.Buffer)
+		stdout = new(bytes.Buffer)
+	)
+
+	cmd := osexec.CommandContext(ctx, settings.MustString("command", nil), args...) //#nosec:G204 // Intended to run configured command
+	cmd.Env = append(os.Environ(), payload.environ()...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = terminationWaitDelay
+
+	if err = cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("command timed out: %w", err)
+		}
+
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > stderrExcerptLength {
+			msg = msg[:stderrExcerptLength] + "…"
+		}
+
+		return fmt.Errorf("running command: %w (stderr: %q)", err, msg)
+	}
+
+	if stdout.Len() > 0 {
+		logrus.WithFields(logrus.Fields{
+			"command": cmd.Path,
+			"stdout":  strings.TrimSpace(stdout.String()),
+		}).Debug("command output")
+	}
+
+	return nil
+}
+
+// ValidateSettings implements the Notifier interface
+func (Notifier) ValidateSettings(settings *fieldcollection.FieldCollection) (err error) {
+	command, err := settings.String("command")
+	if err != nil || command == "" {
+		return fmt.Errorf("command is expected to be non-empty string")
+	}
+
+	if _, err = osexec.LookPath(command); err != nil {
+		return fmt.Errorf("command %q not found: %w", command, err)
+	}
+
+	if settings.HasAll("args") {
+		args, err := settings.StringSlice("args")
+		if err != nil {
+			return fmt.Errorf("args is expected to be list of strings")
+		}
+
+		for i, arg := range args {
+			tpl, err := formatter.ParseTemplate("arg", arg)
+			if err != nil {
+				return fmt.Errorf("parsing arg %d: %w", i, err)
+			}
+
+			if err = formatter.ValidateTemplate(tpl, false); err != nil {
+				return fmt.Errorf("validating arg %d: %w", i, err)
+			}
+		}
+	}
+
+	if settings.HasAll("timeout") {
+		timeout, err := settings.Duration("timeout")
+		if err != nil || timeout <= 0 {
+			return fmt.Errorf("timeout is expected to be positive duration")
+		}
+	}
+
+	return nil
+}
+
+func newEvent(evt notifier.Event, text string) (e event, err error) {
+	vcf := new(bytes.Buffer)
+	if err = vcard.NewEncoder(vcf).Encode(evt.Contact); err != nil {
+		return e, fmt.Errorf("encoding vcard: %w", err)
+	}
+
+	e = event{
+		Account:       evt.Account,
+		Actions:       evt.Actions,
+		Birthday:      evt.Birthday.String(),
+		DaysInAdvance: dateutil.DaysUntil(evt.Date),
+		FormattedName: evt.Contact.PreferredValue(vcard.FieldFormattedName),
+		Milestone:     evt.Milestone,
+		Occurrence:    evt.Date.Format(time.DateOnly),
+		Text:          text,
+		Title:         formatter.FormatNotificationTitle(evt),
+		UID:           evt.Contact.Value(vcard.FieldUID),
+		VCard:         vcf.String(),
+	}
+
+	if age, ok := evt.Birthday.Age(evt.Date); ok {
+		e.Age = &age
+	}
+
+	return e, nil
+}
+
+func (e event) environ() []string {
+	env := []string{
+		"BIRTHDAY_ACCOUNT=" + e.Account,
+		"BIRTHDAY_DATE=" + e.Birthday,
+		"BIRTHDAY_DAYS_IN_ADVANCE=" + strconv.Itoa(e.DaysInAdvance),
+		"BIRTHDAY_FORMATTED_NAME=" + e.FormattedName,
+		"BIRTHDAY_MILESTONE=" + strconv.FormatBool(e.Milestone),
+		"BIRTHDAY_OCCURRENCE=" + e.Occurrence,
+		"BIRTHDAY_TEXT=" + e.Text,
+		"BIRTHDAY_TITLE=" + e.Title,
+		"BIRTHDAY_UID=" + e.UID,
+	}
+
+	if e.Age != nil {
+		env = append(env, "BIRTHDAY_AGE="+strconv.Itoa(*e.Age))
+	}
+
+	for action, actionURL := range e.Actions {
+		env = append(env, "BIRTHDAY_ACTION_"+strings.ToUpper(action)+"_URL="+actionURL)
+	}
+
+	return env
+}
+
+func renderArgs(rawArgs []string, evt notifier.Event) ([]string, error) {
+	args := make([]string, 0, len(rawArgs))
+
+	for i, rawArg := range rawArgs {
+		tpl, err := formatter.ParseTemplate("arg", rawArg)
+		if err != nil {
+			return nil, fmt.Errorf("parsing arg %d: %w", i, err)
+		}
+
+		arg, err := formatter.RenderTemplate(tpl, evt)
+		if err != nil {
+			return nil, fmt.Errorf("rendering arg %d: %w", i, err)
+		}
+
+		args = append(args, arg)
+	}
+
+	return args, nil
+}
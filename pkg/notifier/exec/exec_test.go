@@ -0,0 +1,75 @@
+package exec //revive:disable-line:package-naming // it's a package executing commands
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Luzifer/go_helpers/fieldcollection"
+	"github.com/emersion/go-vcard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/dateutil"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/formatter"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/notifier"
+)
+
+func getTestVCard(t *testing.T) vcard.Card {
+	t.Helper()
+
+	c, err := vcard.NewDecoder(strings.NewReader(`BEGIN:VCARD
+VERSION:4.0
+N:Bloggs;Joe;;;
+FN:Joe Bloggs
+UID:joe
+END:VCARD`)).Decode()
+	require.NoError(t, err)
+
+	return c
+}
+
+func TestSendNotification(t *testing.T) {
+	require.NoError(t, formatter.SetTemplate(formatter.DefaultTemplate))
+
+	var (
+		card = getTestVCard(t)
+		bday = time.Date(time.Now().Year()-30, time.Now().Month(), time.Now().Day(), 0, 0, 0, 0, time.Local)
+		evt  = notifier.Event{Birthday: dateutil.NewBirthday(bday), Contact: card, Date: dateutil.ProjectToNextBirthday(bday)}
+	)
+
+	for name, tc := range map[string]struct {
+		Script    string
+		ErrorPart string
+	}{
+		"success": {
+			Script: `test "$1" = "Joe" && test "$BIRTHDAY_AGE" = "30" && test "$BIRTHDAY_OCCURRENCE" = "$(date +%Y-%m-%d)" && grep -q '"uid":"joe"'`,
+		},
+		"failure with stderr": {
+			Script:    `echo "something broke" >&2; exit 3`,
+			ErrorPart: "something broke",
+		},
+		"timeout": {
+			Script:    `sleep 5`,
+			ErrorPart: "timed out",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			settings := fieldcollection.FromData(map[string]any{
+				"command": "sh",
+				"args":    []any{"-c", tc.Script, "sh", "{{ .contact | getName }}"},
+				"timeout": "500ms",
+			})
+			require.NoError(t, Notifier{}.ValidateSettings(settings))
+
+			err := Notifier{}.SendNotification(settings, evt)
+			if tc.ErrorPart == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.ErrorPart)
+		})
+	}
+}
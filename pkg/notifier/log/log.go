@@ -3,10 +3,8 @@ package log //revive:disable-line:package-naming // it's a package logging the b
 
 import (
 	"fmt"
-	"time"
 
 	"github.com/Luzifer/go_helpers/fieldcollection"
-	"github.com/emersion/go-vcard"
 	"github.com/sirupsen/logrus"
 
 	"git.luzifer.io/luzifer/birthday-notifier/pkg/formatter"
@@ -21,17 +19,17 @@ type (
 var _ notifier.Notifier = Notifier{}
 
 // SendNotification implements the Notifier interface
-func (Notifier) SendNotification(_ *fieldcollection.FieldCollection, contact vcard.Card, when time.Time) error {
-	if contact.Name() == nil {
+func (Notifier) SendNotification(_ *fieldcollection.FieldCollection, evt notifier.Event) error {
+	if evt.Contact.Name() == nil {
 		return fmt.Errorf("contact has no name")
 	}
 
-	text, err := formatter.FormatNotificationText(contact, when)
+	text, err := formatter.FormatNotificationText(evt)
 	if err != nil {
 		return fmt.Errorf("rendering text: %w", err)
 	}
 
-	logrus.WithField("name", contact.Name().GivenName).Info(text)
+	logrus.WithField("name", evt.Contact.Name().GivenName).Info(text)
 	return nil
 }
 
@@ -6,17 +6,72 @@ import (
 
 	"github.com/Luzifer/go_helpers/fieldcollection"
 	"github.com/emersion/go-vcard"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/dateutil"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/photo"
+)
+
+// Actions the user can take on a notification through the links given
+// in Event.Actions
+const (
+	// ActionAcknowledge marks the birthday as handled and stops further
+	// notifications about this occurrence
+	ActionAcknowledge = "acknowledge"
+	// ActionSnooze delivers the notification again later
+	ActionSnooze = "snooze"
+)
+
+// Event types describing when the notification is sent relative to
+// the birthday
+const (
+	// EventTypeAdvance is a notification in advance of the birthday
+	EventTypeAdvance = "advance"
+	// EventTypeAfter is a notification after the birthday
+	EventTypeAfter = "after"
+	// EventTypeToday is a notification on the birthday itself
+	EventTypeToday = "today"
 )
 
 type (
+	// Event describes the birthday a notification is sent for
+	Event struct {
+		// Actions contains the signed URLs to execute the actions (see
+		// ActionAcknowledge and ActionSnooze) on the notification,
+		// empty if actions are not enabled
+		Actions map[string]string
+		// Account is the label of the CardDAV account the contact was
+		// fetched from
+		Account string
+		// Birthday contains the date of birth of the contact
+		Birthday dateutil.Birthday
+		// Contact is the contact having their birthday
+		Contact vcard.Card
+		// Date is the occurrence of the birthday the notification is
+		// about: It is in the future for notifications in advance and in
+		// the past for notifications sent after the birthday
+		Date time.Time
+		// Locale is the name of the locale pack (see i18n package) to
+		// use for the texts of the notification, empty for the default
+		Locale string
+		// Milestone is set when the contact reaches a milestone age at
+		// the occurrence of the birthday
+		Milestone bool
+		// Photo contains the photo of the contact if photos are enabled
+		// and the contact has one
+		Photo *photo.Photo
+		// Template is the name of the template (see formatter package)
+		// to render the notification text with, empty for the default
+		Template string
+	}
+
 	// Notifier specifies what a Notifier can do
 	Notifier interface {
-		// SendNotification will be called with the contact and the
-		// time when the birthday actually is. The method is therefore
-		// also called when a notification in advance is configured and
-		// needs to properly format the notification for that. The settings
-		// passed through this call MUST NOT be stored.
-		SendNotification(settings *fieldcollection.FieldCollection, contact vcard.Card, when time.Time) error
+		// SendNotification will be called with the event describing the
+		// birthday. The method is therefore also called when a
+		// notification in advance is configured and needs to properly
+		// format the notification for that. The settings passed through
+		// this call MUST NOT be stored.
+		SendNotification(settings *fieldcollection.FieldCollection, evt Event) error
 
 		// ValidateSettings is called after configuration load to validate
 		// the settings are suitable for the notifier and do not yield
@@ -24,3 +79,16 @@ type (
 		ValidateSettings(settings *fieldcollection.FieldCollection) error
 	}
 )
+
+// Type returns whether the event is sent in advance of, on or after
+// the birthday
+func (e Event) Type() string {
+	switch d := dateutil.DaysUntil(e.Date); {
+	case d > 0:
+		return EventTypeAdvance
+	case d < 0:
+		return EventTypeAfter
+	default:
+		return EventTypeToday
+	}
+}
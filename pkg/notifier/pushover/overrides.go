@@ -0,0 +1,92 @@
+package pushover
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/Luzifer/go_helpers/fieldcollection"
+)
+
+var overridableSettings = []string{
+	"device",
+	"expire",
+	"html",
+	"priority",
+	"retry",
+	"sound",
+	"ttl",
+	"url",
+	"urlTitle",
+}
+
+// applyOverrides returns a copy of the settings having the override
+// for the given number of days in advance merged into it. If there
+// is no matching override the settings are returned unchanged.
+func applyOverrides(settings *fieldcollection.FieldCollection, daysInAdvance int) (*fieldcollection.FieldCollection, error) {
+	overrides, err := parseOverrides(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	override, ok := overrides[int64(daysInAdvance)]
+	if !ok {
+		return settings, nil
+	}
+
+	merged := settings.Clone()
+	merged.SetFromData(override.Data())
+	return merged, nil
+}
+
+// parseOverrides reads the list of overrides from the settings and
+// returns them indexed by their number of days in advance
+func parseOverrides(settings *fieldcollection.FieldCollection) (map[int64]*fieldcollection.FieldCollection, error) {
+	overrides := make(map[int64]*fieldcollection.FieldCollection)
+
+	if !settings.HasAll("overrides") {
+		return overrides, nil
+	}
+
+	raw, err := settings.Get("overrides")
+	if err != nil {
+		return nil, fmt.Errorf("getting overrides: %w", err)
+	}
+
+	rawList, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("overrides is expected to be a list")
+	}
+
+	for i, rawOverride := range rawList {
+		data, ok := rawOverride.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("override %d is expected to be a map", i)
+		}
+
+		days, err := fieldcollection.FromData(data).Int64("daysInAdvance")
+		if err != nil || days < 0 {
+			return nil, fmt.Errorf("override %d: daysInAdvance is expected to be non-negative integer", i)
+		}
+
+		if _, ok = overrides[days]; ok {
+			return nil, fmt.Errorf("override %d: duplicate override for %d days in advance", i, days)
+		}
+
+		values := fieldcollection.NewFieldCollection()
+		for key, value := range data {
+			if key == "daysInAdvance" {
+				continue
+			}
+
+			if !slices.Contains(overridableSettings, key) {
+				return nil, fmt.Errorf("override %d: setting %q cannot be overridden", i, key)
+			}
+
+			values.Set(key, value)
+		}
+
+		overrides[days] = values
+	}
+
+	return overrides, nil
+}
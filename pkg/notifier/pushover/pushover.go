@@ -3,43 +3,80 @@
 package pushover
 
 import (
+	"bytes"
 	"fmt"
+	"html"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/Luzifer/go_helpers/fieldcollection"
-	"github.com/emersion/go-vcard"
 	"github.com/gregdel/pushover"
 
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/dateutil"
 	"git.luzifer.io/luzifer/birthday-notifier/pkg/formatter"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/i18n"
 	"git.luzifer.io/luzifer/birthday-notifier/pkg/notifier"
 )
 
+const (
+	emergencyMaxExpire = 3 * time.Hour
+	emergencyMinRetry  = 30 * time.Second
+)
+
 type (
 	// Notifier implements the notifier interface
 	Notifier struct{}
 )
 
 var (
-	ptrStrEmpty = func(v string) *string { return &v }("")
+	deviceNameFormat = regexp.MustCompile(`^[A-Za-z0-9_-]{1,25}$`)
+
+	ptrBoolFalse    = func(v bool) *bool { return &v }(false)
+	ptrDurationZero = func(v time.Duration) *time.Duration { return &v }(0)
+	ptrInt64Zero    = func(v int64) *int64 { return &v }(0)
+	ptrStrEmpty     = func(v string) *string { return &v }("")
 
 	_ notifier.Notifier = Notifier{}
 )
 
 // SendNotification implements the Notifier interface
-func (Notifier) SendNotification(settings *fieldcollection.FieldCollection, contact vcard.Card, when time.Time) error {
-	if contact.Name() == nil {
+func (Notifier) SendNotification(settings *fieldcollection.FieldCollection, evt notifier.Event) error {
+	if evt.Contact.Name() == nil {
 		return fmt.Errorf("contact has no name")
 	}
 
-	text, err := formatter.FormatNotificationText(contact, when)
+	text, err := formatter.FormatNotificationText(evt)
 	if err != nil {
 		return fmt.Errorf("rendering text: %w", err)
 	}
 
+	settings, err = applyOverrides(settings, dateutil.DaysUntil(evt.Date))
+	if err != nil {
+		return fmt.Errorf("applying overrides: %w", err)
+	}
+
 	message := &pushover.Message{
-		Message: text,
-		Title:   formatter.FormatNotificationTitle(contact),
-		Sound:   settings.MustString("sound", ptrStrEmpty),
+		DeviceName: settings.MustString("device", ptrStrEmpty),
+		Expire:     settings.MustDuration("expire", ptrDurationZero),
+		HTML:       settings.MustBool("html", ptrBoolFalse),
+		Message:    text,
+		Priority:   int(settings.MustInt64("priority", ptrInt64Zero)),
+		Retry:      settings.MustDuration("retry", ptrDurationZero),
+		Sound:      settings.MustString("sound", ptrStrEmpty),
+		Timestamp:  evt.Date.Unix(),
+		Title:      formatter.FormatNotificationTitle(evt),
+		TTL:        settings.MustDuration("ttl", ptrDurationZero),
+		URL:        settings.MustString("url", ptrStrEmpty),
+		URLTitle:   settings.MustString("urlTitle", ptrStrEmpty),
+	}
+
+	addActions(message, evt)
+
+	if evt.Photo != nil && len(evt.Photo.Data) > 0 {
+		if err = message.AddAttachment(bytes.NewReader(evt.Photo.Data)); err != nil {
+			return fmt.Errorf("attaching photo: %w", err)
+		}
 	}
 
 	if _, err = pushover.New(settings.MustString("apiToken", nil)).
@@ -50,6 +87,32 @@ func (Notifier) SendNotification(settings *fieldcollection.FieldCollection, cont
 	return nil
 }
 
+// addActions links the actions of the event: Acknowledging as the
+// supplementary URL (unless one is configured) and snoozing as a link
+// at the end of the message (Pushover supports only one supplementary
+// URL)
+func addActions(message *pushover.Message, evt notifier.Event) {
+	messages := i18n.Lookup(evt.Locale).Messages
+
+	if ackURL := evt.Actions[notifier.ActionAcknowledge]; ackURL != "" && message.URL == "" && len(ackURL) <= pushover.MessageURLMaxLength {
+		message.URL, message.URLTitle = ackURL, messages.Acknowledge
+	}
+
+	snoozeURL := evt.Actions[notifier.ActionSnooze]
+	if snoozeURL == "" {
+		return
+	}
+
+	link := fmt.Sprintf("%s: %s", messages.Snooze, snoozeURL)
+	if message.HTML {
+		link = fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(snoozeURL), html.EscapeString(messages.Snooze))
+	}
+
+	if text := message.Message + "\n\n" + link; len([]rune(text)) <= pushover.MessageMaxLength {
+		message.Message = text
+	}
+}
+
 // ValidateSettings implements the Notifier interface
 func (Notifier) ValidateSettings(settings *fieldcollection.FieldCollection) (err error) {
 	if v, err := settings.String("apiToken"); err != nil || v == "" {
@@ -60,5 +123,103 @@ func (Notifier) ValidateSettings(settings *fieldcollection.FieldCollection) (err
 		return fmt.Errorf("userKey is expected to be non-empty string")
 	}
 
+	if err = validateMessageSettings(settings); err != nil {
+		return err
+	}
+
+	overrides, err := parseOverrides(settings)
+	if err != nil {
+		return fmt.Errorf("parsing overrides: %w", err)
+	}
+
+	for days, override := range overrides {
+		merged := settings.Clone()
+		merged.SetFromData(override.Data())
+
+		if err = validateMessageSettings(merged); err != nil {
+			return fmt.Errorf("override for %d days in advance: %w", days, err)
+		}
+	}
+
+	return nil
+}
+
+//nolint:gocyclo // Simple checks, splitting them would not help readability
+func validateMessageSettings(settings *fieldcollection.FieldCollection) error {
+	for _, key := range []string{"device", "sound", "url", "urlTitle"} {
+		if settings.HasAll(key) && !settings.CanString(key) {
+			return fmt.Errorf("%s is expected to be string", key)
+		}
+	}
+
+	for _, key := range []string{"expire", "retry", "ttl"} {
+		if settings.HasAll(key) && !settings.CanDuration(key) {
+			return fmt.Errorf("%s is expected to be duration", key)
+		}
+	}
+
+	if settings.HasAll("html") && !settings.CanBool("html") {
+		return fmt.Errorf("html is expected to be boolean")
+	}
+
+	if settings.HasAll("priority") && !settings.CanInt64("priority") {
+		return fmt.Errorf("priority is expected to be integer")
+	}
+
+	var (
+		expire   = settings.MustDuration("expire", ptrDurationZero)
+		priority = settings.MustInt64("priority", ptrInt64Zero)
+		retry    = settings.MustDuration("retry", ptrDurationZero)
+		ttl      = settings.MustDuration("ttl", ptrDurationZero)
+	)
+
+	if priority < pushover.PriorityLowest || priority > pushover.PriorityEmergency {
+		return fmt.Errorf("priority must be between %d and %d", pushover.PriorityLowest, pushover.PriorityEmergency)
+	}
+
+	if priority == pushover.PriorityEmergency {
+		if retry < emergencyMinRetry {
+			return fmt.Errorf("retry must be at least %s for emergency priority", emergencyMinRetry)
+		}
+
+		if expire <= 0 || expire > emergencyMaxExpire {
+			return fmt.Errorf("expire must be between 1s and %s for emergency priority", emergencyMaxExpire)
+		}
+
+		if ttl > 0 {
+			return fmt.Errorf("ttl is not supported for emergency priority")
+		}
+	} else if retry != 0 || expire != 0 {
+		return fmt.Errorf("retry and expire are only valid for emergency priority")
+	}
+
+	if ttl < 0 {
+		return fmt.Errorf("ttl must not be negative")
+	}
+
+	if devices := settings.MustString("device", ptrStrEmpty); devices != "" {
+		for _, device := range strings.Split(devices, ",") {
+			if !deviceNameFormat.MatchString(device) {
+				return fmt.Errorf("device name %q is invalid", device)
+			}
+		}
+	}
+
+	var (
+		url      = settings.MustString("url", ptrStrEmpty)
+		urlTitle = settings.MustString("urlTitle", ptrStrEmpty)
+	)
+
+	switch {
+	case urlTitle != "" && url == "":
+		return fmt.Errorf("urlTitle requires url to be set")
+
+	case len(url) > pushover.MessageURLMaxLength:
+		return fmt.Errorf("url must not exceed %d characters", pushover.MessageURLMaxLength)
+
+	case len([]rune(urlTitle)) > pushover.MessageURLTitleMaxLength:
+		return fmt.Errorf("urlTitle must not exceed %d characters", pushover.MessageURLTitleMaxLength)
+	}
+
 	return nil
 }
@@ -0,0 +1,134 @@
+package pushover
+
+import (
+	"testing"
+
+	"github.com/Luzifer/go_helpers/fieldcollection"
+	"github.com/gregdel/pushover"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/notifier"
+)
+
+func TestValidateSettings(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Settings map[string]any
+		Valid    bool
+	}{
+		"minimal": {
+			Settings: map[string]any{},
+			Valid:    true,
+		},
+		"full": {
+			Settings: map[string]any{"device": "phone,tablet", "html": true, "priority": 1, "ttl": "24h", "url": "https://example.com", "urlTitle": "Example"},
+			Valid:    true,
+		},
+		"emergency": {
+			Settings: map[string]any{"priority": 2, "retry": "5m", "expire": "2h"},
+			Valid:    true,
+		},
+		"emergency without retry": {
+			Settings: map[string]any{"priority": 2, "expire": "2h"},
+		},
+		"emergency with too long expire": {
+			Settings: map[string]any{"priority": 2, "retry": "5m", "expire": "4h"},
+		},
+		"emergency with ttl": {
+			Settings: map[string]any{"priority": 2, "retry": "5m", "expire": "2h", "ttl": "1h"},
+		},
+		"retry without emergency": {
+			Settings: map[string]any{"priority": 1, "retry": "5m"},
+		},
+		"priority out of range": {
+			Settings: map[string]any{"priority": 3},
+		},
+		"invalid device": {
+			Settings: map[string]any{"device": "my phone"},
+		},
+		"urlTitle without url": {
+			Settings: map[string]any{"urlTitle": "Example"},
+		},
+		"valid overrides": {
+			Settings: map[string]any{"priority": -1, "overrides": []any{
+				map[string]any{"daysInAdvance": 0, "priority": 2, "retry": "1m", "expire": "1h"},
+				map[string]any{"daysInAdvance": 7, "priority": -2},
+			}},
+			Valid: true,
+		},
+		"invalid override combination": {
+			Settings: map[string]any{"overrides": []any{
+				map[string]any{"daysInAdvance": 0, "priority": 2},
+			}},
+		},
+		"duplicate override": {
+			Settings: map[string]any{"overrides": []any{
+				map[string]any{"daysInAdvance": 0, "priority": 1},
+				map[string]any{"daysInAdvance": 0, "priority": 2},
+			}},
+		},
+		"override of credentials": {
+			Settings: map[string]any{"overrides": []any{
+				map[string]any{"daysInAdvance": 0, "userKey": "other"},
+			}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			settings := fieldcollection.FromData(tc.Settings)
+			settings.Set("apiToken", "token")
+			settings.Set("userKey", "user")
+
+			err := Notifier{}.ValidateSettings(settings)
+			if tc.Valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestApplyOverrides(t *testing.T) {
+	settings := fieldcollection.FromData(map[string]any{
+		"priority": -1,
+		"sound":    "pushover",
+		"overrides": []any{
+			map[string]any{"daysInAdvance": 0, "priority": 1},
+		},
+	})
+
+	merged, err := applyOverrides(settings, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), merged.MustInt64("priority", nil))
+	assert.Equal(t, "pushover", merged.MustString("sound", nil))
+	assert.Equal(t, int64(-1), settings.MustInt64("priority", nil), "original settings must not be modified")
+
+	merged, err = applyOverrides(settings, 3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), merged.MustInt64("priority", nil))
+}
+
+func TestAddActions(t *testing.T) {
+	evt := notifier.Event{Actions: map[string]string{
+		notifier.ActionAcknowledge: "https://example.com/action/acknowledge",
+		notifier.ActionSnooze:      "https://example.com/action/snooze",
+	}}
+
+	message := &pushover.Message{Message: "text"}
+	addActions(message, evt)
+	assert.Equal(t, "https://example.com/action/acknowledge", message.URL)
+	assert.Equal(t, "Done", message.URLTitle)
+	assert.Equal(t, "text\n\nRemind me later: https://example.com/action/snooze", message.Message)
+
+	// Configured URL is kept, HTML messages get a link
+	message = &pushover.Message{Message: "text", HTML: true, URL: "https://example.com"}
+	addActions(message, evt)
+	assert.Equal(t, "https://example.com", message.URL)
+	assert.Equal(t, `text
+
+<a href="https://example.com/action/snooze">Remind me later</a>`, message.Message)
+
+	message = &pushover.Message{Message: "text"}
+	addActions(message, notifier.Event{})
+	assert.Equal(t, &pushover.Message{Message: "text"}, message)
+}
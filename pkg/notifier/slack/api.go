@@ -0,0 +1,115 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const apiBaseURL = "https://slack.com/api/"
+
+type (
+	apiResponse struct {
+		Error   string `json:"error"`
+		OK      bool   `json:"ok"`
+		TS      string `json:"ts"`
+		Warning string `json:"warning"`
+
+		User struct {
+			ID string `json:"id"`
+		} `json:"user"`
+	}
+)
+
+// lookupUserByEmail resolves the email of a workspace member into the
+// users ID which then can be used as channel to send a direct message
+func lookupUserByEmail(token, email string) (string, error) {
+	params := url.Values{"email": []string{email}}
+
+	resp, err := callAPI(token, http.MethodGet, "users.lookupByEmail?"+params.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	return resp.User.ID, nil
+}
+
+// postMessage sends the message using the chat.postMessage method and
+// returns the timestamp of the created message
+func postMessage(token string, msg message) (string, error) {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return "", fmt.Errorf("encoding message: %w", err)
+	}
+
+	resp, err := callAPI(token, http.MethodPost, "chat.postMessage", payload)
+	if err != nil {
+		return "", err
+	}
+
+	return resp.TS, nil
+}
+
+func callAPI(token, httpMethod, method string, payload []byte) (*apiResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookPostTimeout)
+	defer cancel()
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, httpMethod, apiBaseURL+method, body)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("executing request: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logrus.WithError(err).Error("closing slack response body (leaked fd)")
+		}
+	}()
+
+	apiMethod, _, _ := strings.Cut(method, "?")
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// Errors are reported in the body
+
+	case http.StatusTooManyRequests:
+		return nil, Error{Code: "ratelimited", Method: apiMethod, Status: resp.StatusCode}
+
+	default:
+		return nil, Error{Code: http.StatusText(resp.StatusCode), Method: apiMethod, Status: resp.StatusCode}
+	}
+
+	var apiResp apiResponse
+	if err = json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	if !apiResp.OK {
+		return nil, Error{Code: apiResp.Error, Method: apiMethod, Status: resp.StatusCode}
+	}
+
+	if apiResp.Warning != "" {
+		logrus.WithField("method", apiMethod).WithField("warning", apiResp.Warning).Warn("slack api returned warning")
+	}
+
+	return &apiResp, nil
+}
@@ -0,0 +1,138 @@
+package slack
+
+import (
+	"fmt"
+	"strconv"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/dateutil"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/formatter"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/i18n"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/notifier"
+)
+
+type (
+	block struct {
+		Type      string       `json:"type"`
+		Accessory *image       `json:"accessory,omitempty"`
+		Elements  []any        `json:"elements,omitempty"`
+		Fields    []textObject `json:"fields,omitempty"`
+		Text      *textObject  `json:"text,omitempty"`
+	}
+
+	button struct {
+		Type     string     `json:"type"`
+		ActionID string     `json:"action_id"`
+		Style    string     `json:"style,omitempty"`
+		Text     textObject `json:"text"`
+		URL      string     `json:"url"`
+	}
+
+	image struct {
+		Type     string `json:"type"`
+		AltText  string `json:"alt_text"`
+		ImageURL string `json:"image_url"`
+	}
+
+	textObject struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+)
+
+// buildBlocks creates a Block Kit layout consisting of a header with
+// the notification title, a section with the rendered text and the
+// age (if the year of birth is known) and a context showing when the
+// birthday is going to happen or has happened. Photos given as URL are
+// shown next to the text, inline photos cannot be referenced by Slack.
+// If actions are enabled they are added as link buttons.
+func buildBlocks(evt notifier.Event, text string) []block {
+	section := block{
+		Type: "section",
+		Text: &textObject{Type: "mrkdwn", Text: text},
+	}
+
+	if evt.Photo != nil && evt.Photo.URL != "" {
+		section.Accessory = &image{
+			Type:     "image",
+			AltText:  formatter.FormatNotificationTitle(evt),
+			ImageURL: evt.Photo.URL,
+		}
+	}
+
+	var (
+		d      = dateutil.DaysUntil(evt.Date)
+		locale = i18n.Lookup(evt.Locale)
+		msgs   = locale.Messages
+	)
+
+	if age, ok := evt.Birthday.Age(evt.Date); ok {
+		label := msgs.Turning
+		if d < 0 {
+			label = msgs.Turned
+		}
+
+		section.Fields = append(section.Fields, textObject{
+			Type: "mrkdwn",
+			Text: "*" + label + "*\n" + strconv.Itoa(age),
+		})
+	}
+
+	var daysUntil string
+	switch {
+	case d == 0:
+		daysUntil = msgs.Today
+	case d == 1:
+		daysUntil = msgs.Tomorrow
+	case d == -1:
+		daysUntil = msgs.Yesterday
+	case d < 0:
+		daysUntil = locale.Count(msgs.DaysAgo, -d)
+	default:
+		daysUntil = locale.Count(msgs.InDays, d)
+	}
+
+	blocks := []block{
+		{
+			Type: "header",
+			Text: &textObject{Type: "plain_text", Text: formatter.FormatNotificationTitle(evt)},
+		},
+		section,
+		{
+			Type: "context",
+			Elements: []any{
+				textObject{Type: "mrkdwn", Text: fmt.Sprintf(":birthday: %s (%s)", daysUntil, locale.Date(evt.Date))},
+			},
+		},
+	}
+
+	if buttons := actionButtons(evt, msgs); len(buttons) > 0 {
+		blocks = append(blocks, block{Type: "actions", Elements: buttons})
+	}
+
+	return blocks
+}
+
+// actionButtons creates link buttons for the actions of the event
+func actionButtons(evt notifier.Event, msgs i18n.Messages) (buttons []any) {
+	for _, a := range []struct {
+		action, label, style string
+	}{
+		{notifier.ActionAcknowledge, msgs.Acknowledge, "primary"},
+		{notifier.ActionSnooze, msgs.Snooze, ""},
+	} {
+		actionURL := evt.Actions[a.action]
+		if actionURL == "" {
+			continue
+		}
+
+		buttons = append(buttons, button{
+			Type:     "button",
+			ActionID: a.action,
+			Style:    a.style,
+			Text:     textObject{Type: "plain_text", Text: a.label},
+			URL:      actionURL,
+		})
+	}
+
+	return buttons
+}
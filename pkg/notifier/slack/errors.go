@@ -0,0 +1,64 @@
+package slack
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type (
+	// Error represents an error reported by Slack either as response
+	// body of a webhook or as error field of an API response
+	Error struct {
+		Code   string
+		Method string
+		Status int
+	}
+)
+
+var (
+	errorCodeFormat = regexp.MustCompile(`^[a-z_]+$`)
+
+	errorDescriptions = map[string]string{
+		"action_prohibited":                 "posting was prohibited by an admin",
+		"channel_is_archived":               "channel is archived",
+		"channel_not_found":                 "channel does not exist or is not visible to the bot",
+		"invalid_auth":                      "token is invalid",
+		"invalid_blocks":                    "blocks are invalid",
+		"invalid_payload":                   "payload could not be understood by Slack",
+		"invalid_token":                     "webhook token is invalid",
+		"is_archived":                       "channel is archived",
+		"missing_scope":                     "token lacks a required scope",
+		"no_active_hooks":                   "webhook is disabled",
+		"no_service":                        "webhook is disabled or was removed",
+		"no_service_id":                     "webhook is invalid",
+		"no_text":                           "message is missing a text",
+		"not_authed":                        "no token was provided",
+		"not_in_channel":                    "bot is not a member of the channel",
+		"posting_to_general_channel_denied": "bot is not allowed to post to the general channel",
+		"ratelimited":                       "rate-limit exceeded",
+		"team_disabled":                     "workspace is disabled",
+		"users_not_found":                   "no user found for the given email",
+	}
+)
+
+// newWebhookError parses the plain-text body returned by a webhook
+// into an Error, keeping an excerpt of the body in case it does not
+// look like one of Slacks error codes
+func newWebhookError(status int, body string) Error {
+	body = strings.TrimSpace(body)
+	if !errorCodeFormat.MatchString(body) {
+		return Error{Code: fmt.Sprintf("unexpected response %q", body), Method: "webhook", Status: status}
+	}
+
+	return Error{Code: body, Method: "webhook", Status: status}
+}
+
+func (e Error) Error() string {
+	desc, ok := errorDescriptions[e.Code]
+	if !ok {
+		return fmt.Sprintf("%s failed (status %d): %s", e.Method, e.Status, e.Code)
+	}
+
+	return fmt.Sprintf("%s failed (status %d): %s (%s)", e.Method, e.Status, desc, e.Code)
+}
@@ -1,66 +1,193 @@
 // Package slack provides a notifier to send birthday notifications
-// through a Slack(-compatible) WebHook
+// through a Slack(-compatible) WebHook or the Slack API using a bot
+// token
 package slack
 
 import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/Luzifer/go_helpers/fieldcollection"
-	"github.com/emersion/go-vcard"
 	"github.com/sirupsen/logrus"
 
 	"git.luzifer.io/luzifer/birthday-notifier/pkg/formatter"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/i18n"
 	"git.luzifer.io/luzifer/birthday-notifier/pkg/notifier"
 )
 
-const webhookPostTimeout = 2 * time.Second
+const (
+	errorBodyExcerptLength = 1024
+	webhookPostTimeout     = 2 * time.Second
+)
 
 type (
 	// Notifier implements the notifier interface
 	Notifier struct{}
+
+	message struct {
+		Blocks    []block `json:"blocks,omitempty"`
+		Channel   string  `json:"channel,omitempty"`
+		IconEmoji string  `json:"icon_emoji,omitempty"`
+		Text      string  `json:"text"`
+		ThreadTS  string  `json:"thread_ts,omitempty"`
+		Username  string  `json:"username,omitempty"`
+	}
 )
 
 var (
-	ptrStrEmpty = func(v string) *string { return &v }("")
+	ptrBoolFalse  = func(v bool) *bool { return &v }(false)
+	ptrStrEmpty   = func(v string) *string { return &v }("")
+	ptrSliceEmpty = func(v []string) *[]string { return &v }(nil)
 
 	_ notifier.Notifier = Notifier{}
 )
 
 // SendNotification implements the Notifier interface
-func (Notifier) SendNotification(settings *fieldcollection.FieldCollection, contact vcard.Card, when time.Time) error {
-	if contact.Name() == nil {
+func (Notifier) SendNotification(settings *fieldcollection.FieldCollection, evt notifier.Event) error {
+	if evt.Contact.Name() == nil {
 		return fmt.Errorf("contact has no name")
 	}
 
-	text, err := formatter.FormatNotificationText(contact, when)
+	text, err := formatter.FormatNotificationText(evt)
 	if err != nil {
 		return fmt.Errorf("rendering text: %w", err)
 	}
 
-	payload := new(bytes.Buffer)
-	if err = json.NewEncoder(payload).Encode(struct {
-		Channel   string `json:"channel,omitempty"`
-		IconEmoji string `json:"icon_emoji,omitempty"`
-		Text      string `json:"text"`
-		Username  string `json:"username,omitempty"`
-	}{
+	msg := message{
 		Channel:   settings.MustString("channel", ptrStrEmpty),
 		IconEmoji: settings.MustString("iconEmoji", ptrStrEmpty),
 		Text:      text,
+		ThreadTS:  settings.MustString("threadTS", ptrStrEmpty),
 		Username:  settings.MustString("username", ptrStrEmpty),
-	}); err != nil {
+	}
+
+	if settings.MustBool("blocks", ptrBoolFalse) {
+		msg.Blocks = buildBlocks(evt, text)
+	} else {
+		msg.Text += actionLinks(evt)
+	}
+
+	if token := settings.MustString("botToken", ptrStrEmpty); token != "" {
+		return sendViaAPI(token, settings.MustStringSlice("userEmails", ptrSliceEmpty), msg)
+	}
+
+	return sendViaWebhook(settings.MustString("webhook", nil), msg)
+}
+
+// actionLinks renders the links to the actions of the event to be
+// appended to the text of messages without blocks
+func actionLinks(evt notifier.Event) string {
+	msgs := i18n.Lookup(evt.Locale).Messages
+
+	var links []string
+	for _, a := range []struct {
+		action, label string
+	}{
+		{notifier.ActionAcknowledge, msgs.Acknowledge},
+		{notifier.ActionSnooze, msgs.Snooze},
+	} {
+		if actionURL := evt.Actions[a.action]; actionURL != "" {
+			links = append(links, fmt.Sprintf("<%s|%s>", actionURL, a.label))
+		}
+	}
+
+	if len(links) == 0 {
+		return ""
+	}
+
+	return "\n\n" + strings.Join(links, " · ")
+}
+
+// ValidateSettings implements the Notifier interface
+func (Notifier) ValidateSettings(settings *fieldcollection.FieldCollection) (err error) {
+	var (
+		botToken = settings.MustString("botToken", ptrStrEmpty)
+		webhook  = settings.MustString("webhook", ptrStrEmpty)
+	)
+
+	switch {
+	case botToken == "" && webhook == "":
+		return fmt.Errorf("either webhook or botToken is expected to be non-empty string")
+
+	case botToken != "" && webhook != "":
+		return fmt.Errorf("only one of webhook and botToken must be set")
+	}
+
+	if settings.HasAll("blocks") && !settings.CanBool("blocks") {
+		return fmt.Errorf("blocks is expected to be boolean")
+	}
+
+	if settings.HasAll("userEmails") && !settings.CanStringSlice("userEmails") {
+		return fmt.Errorf("userEmails is expected to be list of strings")
+	}
+
+	if botToken == "" {
+		if len(settings.MustStringSlice("userEmails", ptrSliceEmpty)) > 0 {
+			return fmt.Errorf("userEmails is only supported with botToken")
+		}
+
+		if settings.MustString("threadTS", ptrStrEmpty) != "" {
+			return fmt.Errorf("threadTS is only supported with botToken")
+		}
+
+		return nil
+	}
+
+	if settings.MustString("channel", ptrStrEmpty) == "" && len(settings.MustStringSlice("userEmails", ptrSliceEmpty)) == 0 {
+		return fmt.Errorf("channel or userEmails is required when using botToken")
+	}
+
+	return nil
+}
+
+// sendViaAPI posts the message to the configured channel and to each
+// user resolved from the given emails as a direct message
+func sendViaAPI(token string, userEmails []string, msg message) error {
+	var (
+		errs    []error
+		targets []string
+	)
+
+	if msg.Channel != "" {
+		targets = append(targets, msg.Channel)
+	}
+
+	for _, email := range userEmails {
+		userID, err := lookupUserByEmail(token, email)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("looking up user %q: %w", email, err))
+			continue
+		}
+		targets = append(targets, userID)
+	}
+
+	for _, target := range targets {
+		msg.Channel = target
+		if _, err := postMessage(token, msg); err != nil {
+			errs = append(errs, fmt.Errorf("posting message to %q: %w", target, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func sendViaWebhook(webhook string, msg message) error {
+	payload := new(bytes.Buffer)
+	if err := json.NewEncoder(payload).Encode(msg); err != nil {
 		return fmt.Errorf("encoding hook payload: %w", err)
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), webhookPostTimeout)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, settings.MustString("webhook", nil), payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, payload)
 	if err != nil {
 		return fmt.Errorf("creating request: %w", err)
 	}
@@ -77,16 +204,12 @@ func (Notifier) SendNotification(settings *fieldcollection.FieldCollection, cont
 	}()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status %d", resp.StatusCode)
-	}
-
-	return nil
-}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, errorBodyExcerptLength))
+		if err != nil {
+			return fmt.Errorf("reading error response (status %d): %w", resp.StatusCode, err)
+		}
 
-// ValidateSettings implements the Notifier interface
-func (Notifier) ValidateSettings(settings *fieldcollection.FieldCollection) (err error) {
-	if v, err := settings.String("webhook"); err != nil || v == "" {
-		return fmt.Errorf("webhook is expected to be non-empty string")
+		return newWebhookError(resp.StatusCode, string(body))
 	}
 
 	return nil
@@ -0,0 +1,208 @@
+package slack
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Luzifer/go_helpers/fieldcollection"
+	"github.com/emersion/go-vcard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/dateutil"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/formatter"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/notifier"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/photo"
+)
+
+func getTestEvent(t *testing.T, daysInAdvance int) notifier.Event {
+	t.Helper()
+
+	c, err := vcard.NewDecoder(strings.NewReader("BEGIN:VCARD\nVERSION:4.0\nN:Bloggs;Joe;;;\nFN:Joe Bloggs\nEND:VCARD")).Decode()
+	require.NoError(t, err)
+
+	occurrence := dateutil.TodayStartOfDay().AddDate(0, 0, daysInAdvance)
+	return notifier.Event{
+		Birthday: dateutil.NewBirthday(occurrence.AddDate(-30, 0, 0)),
+		Contact:  c,
+		Date:     occurrence,
+	}
+}
+
+func TestWebhookError(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Status   int
+		Body     string
+		Code     string
+		Expected string
+	}{
+		"known code": {
+			Status:   http.StatusForbidden,
+			Body:     "invalid_token\n",
+			Code:     "invalid_token",
+			Expected: "webhook failed (status 403): webhook token is invalid (invalid_token)",
+		},
+		"unknown code": {
+			Status:   http.StatusBadRequest,
+			Body:     "some_new_error",
+			Code:     "some_new_error",
+			Expected: "webhook failed (status 400): some_new_error",
+		},
+		"no error code": {
+			Status:   http.StatusBadGateway,
+			Body:     "<html>Bad Gateway</html>",
+			Code:     `unexpected response "<html>Bad Gateway</html>"`,
+			Expected: `webhook failed (status 502): unexpected response "<html>Bad Gateway</html>"`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := newWebhookError(tc.Status, tc.Body)
+			assert.Equal(t, tc.Code, err.Code)
+			assert.Equal(t, tc.Status, err.Status)
+			assert.Equal(t, tc.Expected, err.Error())
+		})
+	}
+
+	assert.Equal(t,
+		"chat.postMessage failed (status 200): bot is not a member of the channel (not_in_channel)",
+		Error{Code: "not_in_channel", Method: "chat.postMessage", Status: http.StatusOK}.Error())
+}
+
+func TestBuildBlocks(t *testing.T) {
+	require.NoError(t, formatter.SetTemplate(formatter.DefaultTemplate))
+
+	evt := getTestEvent(t, 3)
+	blocks := buildBlocks(evt, "text")
+	require.Len(t, blocks, 3)
+
+	assert.Equal(t, "header", blocks[0].Type)
+	assert.Equal(t, formatter.FormatNotificationTitle(evt), blocks[0].Text.Text)
+
+	assert.Equal(t, "section", blocks[1].Type)
+	assert.Equal(t, "text", blocks[1].Text.Text)
+	assert.Nil(t, blocks[1].Accessory)
+	assert.Equal(t, []textObject{{Type: "mrkdwn", Text: "*Turning*\n30"}}, blocks[1].Fields)
+
+	assert.Equal(t, "context", blocks[2].Type)
+	require.Len(t, blocks[2].Elements, 1)
+	assert.Contains(t, blocks[2].Elements[0].(textObject).Text, ":birthday: In 3 days")
+
+	// Past birthdays, photo and actions
+	evt = getTestEvent(t, -1)
+	evt.Photo = &photo.Photo{URL: "https://example.com/joe.jpg"}
+	evt.Actions = map[string]string{
+		notifier.ActionAcknowledge: "https://example.com/action/acknowledge",
+		notifier.ActionSnooze:      "https://example.com/action/snooze",
+	}
+
+	blocks = buildBlocks(evt, "text")
+	require.Len(t, blocks, 4)
+
+	require.NotNil(t, blocks[1].Accessory)
+	assert.Equal(t, "https://example.com/joe.jpg", blocks[1].Accessory.ImageURL)
+	assert.Equal(t, []textObject{{Type: "mrkdwn", Text: "*Turned*\n30"}}, blocks[1].Fields)
+	assert.Contains(t, blocks[2].Elements[0].(textObject).Text, ":birthday: Yesterday")
+
+	assert.Equal(t, "actions", blocks[3].Type)
+	require.Len(t, blocks[3].Elements, 2)
+	assert.Equal(t, "https://example.com/action/acknowledge", blocks[3].Elements[0].(button).URL)
+	assert.Equal(t, "primary", blocks[3].Elements[0].(button).Style)
+	assert.Equal(t, notifier.ActionSnooze, blocks[3].Elements[1].(button).ActionID)
+
+	// Unknown year of birth
+	evt = getTestEvent(t, 0)
+	evt.Birthday.Year = 0
+	blocks = buildBlocks(evt, "text")
+	assert.Empty(t, blocks[1].Fields)
+	assert.Contains(t, blocks[2].Elements[0].(textObject).Text, ":birthday: Today")
+}
+
+func TestSendViaWebhook(t *testing.T) {
+	require.NoError(t, formatter.SetTemplate(formatter.DefaultTemplate))
+
+	var msg message
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/disabled" {
+			http.Error(w, "no_active_hooks", http.StatusForbidden)
+			return
+		}
+
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&msg))
+	}))
+	defer srv.Close()
+
+	settings := fieldcollection.FromData(map[string]any{"webhook": srv.URL + "/hook", "blocks": true, "username": "bot"})
+	require.NoError(t, Notifier{}.SendNotification(settings, getTestEvent(t, 0)))
+	assert.Equal(t, "bot", msg.Username)
+	assert.Contains(t, msg.Text, "Joe")
+	assert.Len(t, msg.Blocks, 3)
+
+	// Without blocks the action links are appended to the text
+	msg = message{}
+	evt := getTestEvent(t, 0)
+	evt.Actions = map[string]string{
+		notifier.ActionAcknowledge: "https://example.com/action/acknowledge",
+		notifier.ActionSnooze:      "https://example.com/action/snooze",
+	}
+	require.NoError(t, Notifier{}.SendNotification(fieldcollection.FromData(map[string]any{"webhook": srv.URL + "/hook"}), evt))
+	assert.Empty(t, msg.Blocks)
+	assert.True(t, strings.HasSuffix(msg.Text, "\n\n<https://example.com/action/acknowledge|Done> · <https://example.com/action/snooze|Remind me later>"), msg.Text)
+
+	err := Notifier{}.SendNotification(fieldcollection.FromData(map[string]any{"webhook": srv.URL + "/disabled"}), getTestEvent(t, 0))
+	var slackErr Error
+	require.ErrorAs(t, err, &slackErr)
+	assert.Equal(t, "no_active_hooks", slackErr.Code)
+}
+
+func TestValidateSettings(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Settings map[string]any
+		Valid    bool
+	}{
+		"webhook": {
+			Settings: map[string]any{"webhook": "https://hooks.slack.com/services/T/B/X", "blocks": true},
+			Valid:    true,
+		},
+		"bot token with channel": {
+			Settings: map[string]any{"botToken": "xoxb-1", "channel": "C123", "threadTS": "1700000000.000100"},
+			Valid:    true,
+		},
+		"bot token with user emails": {
+			Settings: map[string]any{"botToken": "xoxb-1", "userEmails": []any{"joe@example.com"}},
+			Valid:    true,
+		},
+		"nothing": {
+			Settings: map[string]any{},
+		},
+		"webhook and bot token": {
+			Settings: map[string]any{"webhook": "https://hooks.slack.com/services/T/B/X", "botToken": "xoxb-1"},
+		},
+		"invalid blocks": {
+			Settings: map[string]any{"webhook": "https://hooks.slack.com/services/T/B/X", "blocks": []string{"yes"}},
+		},
+		"invalid user emails": {
+			Settings: map[string]any{"botToken": "xoxb-1", "userEmails": 5},
+		},
+		"user emails with webhook": {
+			Settings: map[string]any{"webhook": "https://hooks.slack.com/services/T/B/X", "userEmails": []any{"joe@example.com"}},
+		},
+		"thread with webhook": {
+			Settings: map[string]any{"webhook": "https://hooks.slack.com/services/T/B/X", "threadTS": "1700000000.000100"},
+		},
+		"bot token without target": {
+			Settings: map[string]any{"botToken": "xoxb-1"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := Notifier{}.ValidateSettings(fieldcollection.FromData(tc.Settings))
+			if tc.Valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,338 @@
+// Package photo contains a loader for the PHOTO of contacts (inline or
+// referenced by URL) resizing them to be attached to notifications
+package photo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // Register GIF decoder
+	"image/jpeg"
+	_ "image/png" // Register PNG decoder
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emersion/go-vcard"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/image/draw"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/config"
+)
+
+// Defaults used for unset values in the configuration
+const (
+	DefaultMaxBytes = 1 << 20 // 1 MiB
+	DefaultSize     = 128
+	DefaultTimeout  = 5 * time.Second
+)
+
+const (
+	// ContentType is the type of the resized photos
+	ContentType = "image/jpeg"
+
+	// failureRetention is the time failed loads are not retried for so
+	// a broken photo does not delay every notification of a run
+	failureRetention = 15 * time.Minute
+	jpegQuality      = 85
+	maxCacheEntries  = 256
+	maxPixels        = 50_000_000
+)
+
+type (
+	// Loader extracts, fetches, resizes and caches photos. A nil
+	// Loader does not load any photos.
+	Loader struct {
+		cfg    config.PhotoConfig
+		client *http.Client
+
+		cache      map[string]*Photo
+		cacheOrder []string
+		cacheLock  sync.Mutex
+		failures   map[string]failure
+	}
+
+	failure struct {
+		err   error
+		until time.Time
+	}
+
+	// Photo contains the resized photo of a contact
+	Photo struct {
+		// ContentType is the MIME type of Data
+		ContentType string
+		// Data contains the resized image or is empty if the photo is
+		// given as URL and fetching is disabled
+		Data []byte
+		// URL is the URL the photo was given as, empty for inline photos
+		URL string
+	}
+)
+
+// ErrTooLarge signals the photo exceeds the configured maximum size
+var ErrTooLarge = errors.New("photo too large")
+
+// New validates the configuration and creates a new Loader
+func New(cfg config.PhotoConfig) (*Loader, error) {
+	switch {
+	case cfg.MaxBytes < 0:
+		return nil, fmt.Errorf("maxBytes must not be negative")
+	case cfg.Size < 0:
+		return nil, fmt.Errorf("size must not be negative")
+	case cfg.Timeout < 0:
+		return nil, fmt.Errorf("timeout must not be negative")
+	}
+
+	if cfg.MaxBytes == 0 {
+		cfg.MaxBytes = DefaultMaxBytes
+	}
+
+	if cfg.Size == 0 {
+		cfg.Size = DefaultSize
+	}
+
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+
+	return &Loader{
+		cfg:      cfg,
+		client:   &http.Client{Timeout: cfg.Timeout},
+		cache:    make(map[string]*Photo),
+		failures: make(map[string]failure),
+	}, nil
+}
+
+// Get returns the photo of the contact or nil if the contact has no
+// photo. Results are cached by the content of the PHOTO property,
+// failures are cached for a short time.
+func (l *Loader) Get(contact vcard.Card) (*Photo, error) {
+	if l == nil {
+		return nil, nil
+	}
+
+	field := contact.Preferred(vcard.FieldPhoto)
+	if field == nil || strings.TrimSpace(field.Value) == "" {
+		return nil, nil
+	}
+
+	key := cacheKey(field)
+
+	l.cacheLock.Lock()
+	p, ok := l.cache[key]
+	f, failed := l.failures[key]
+	l.cacheLock.Unlock()
+
+	switch {
+	case ok:
+		return p, nil
+	case failed && time.Now().Before(f.until):
+		return nil, f.err
+	}
+
+	p, err := l.load(field)
+
+	l.cacheLock.Lock()
+	defer l.cacheLock.Unlock()
+
+	if err != nil {
+		for k, f := range l.failures {
+			if time.Now().After(f.until) {
+				delete(l.failures, k)
+			}
+		}
+		l.failures[key] = failure{err: err, until: time.Now().Add(failureRetention)}
+		return nil, err
+	}
+	delete(l.failures, key)
+
+	if len(l.cacheOrder) >= maxCacheEntries {
+		delete(l.cache, l.cacheOrder[0])
+		l.cacheOrder = l.cacheOrder[1:]
+	}
+	l.cache[key] = p
+	l.cacheOrder = append(l.cacheOrder, key)
+
+	return p, nil
+}
+
+// fetch downloads the photo from the URL limited in time and size
+func (l *Loader) fetch(url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), l.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("executing request: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logrus.WithError(err).Error("closing photo response body (leaked fd)")
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return readLimited(resp.Body, l.cfg.MaxBytes)
+}
+
+// load extracts the raw image from the field (fetching it if allowed)
+// and resizes it
+func (l *Loader) load(field *vcard.Field) (*Photo, error) {
+	var (
+		p   = &Photo{ContentType: ContentType}
+		raw []byte
+		err error
+	)
+
+	value := strings.TrimSpace(field.Value)
+
+	switch {
+	case strings.HasPrefix(value, "data:"):
+		if raw, err = decodeDataURI(value); err != nil {
+			return nil, fmt.Errorf("decoding data uri: %w", err)
+		}
+
+	case isBase64Encoded(field):
+		if raw, err = decodeBase64(value); err != nil {
+			return nil, fmt.Errorf("decoding base64: %w", err)
+		}
+
+	case strings.HasPrefix(value, "https://"), strings.HasPrefix(value, "http://"):
+		p.URL = value
+		if !l.cfg.Fetch {
+			return p, nil
+		}
+
+		if raw, err = l.fetch(value); err != nil {
+			return nil, fmt.Errorf("fetching photo: %w", err)
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported photo value")
+	}
+
+	if int64(len(raw)) > l.cfg.MaxBytes {
+		return nil, ErrTooLarge
+	}
+
+	if p.Data, err = resize(raw, l.cfg.Size); err != nil {
+		return nil, fmt.Errorf("resizing photo: %w", err)
+	}
+
+	return p, nil
+}
+
+func cacheKey(field *vcard.Field) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%v\x00%s", field.Params, field.Value)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// decodeBase64 decodes base64 content ignoring whitespace introduced
+// by line folding
+func decodeBase64(value string) ([]byte, error) {
+	value = strings.Join(strings.Fields(value), "")
+
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("decoding: %w", err)
+	}
+
+	return data, nil
+}
+
+// decodeDataURI decodes a `data:image/jpeg;base64,...` URI as used in
+// vCard 4.0
+func decodeDataURI(value string) ([]byte, error) {
+	meta, data, ok := strings.Cut(strings.TrimPrefix(value, "data:"), ",")
+	if !ok {
+		return nil, fmt.Errorf("missing data")
+	}
+
+	if !strings.HasSuffix(meta, ";base64") {
+		return nil, fmt.Errorf("only base64 encoded data is supported")
+	}
+
+	return decodeBase64(data)
+}
+
+// isBase64Encoded checks for the vCard 2.1 / 3.0 way of inlining photos
+// (`PHOTO;ENCODING=b;TYPE=JPEG:...`)
+func isBase64Encoded(field *vcard.Field) bool {
+	switch strings.ToLower(field.Params.Get("ENCODING")) {
+	case "b", "base64":
+		return true
+	default:
+		return false
+	}
+}
+
+// readLimited reads the reader returning ErrTooLarge if it contains
+// more than maxBytes
+func readLimited(r io.Reader, maxBytes int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading: %w", err)
+	}
+
+	if int64(len(data)) > maxBytes {
+		return nil, ErrTooLarge
+	}
+
+	return data, nil
+}
+
+// resize scales the image to fit into a square of size pixels (images
+// are never scaled up) and encodes it as JPEG
+func resize(raw []byte, size int) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("decoding image config: %w", err)
+	}
+
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("decoding image: %w", err)
+	}
+
+	width, height := src.Bounds().Dx(), src.Bounds().Dy()
+	if width > size || height > size {
+		if width > height {
+			width, height = size, max(1, height*size/width)
+		} else {
+			width, height = max(1, width*size/height), size
+		}
+	}
+
+	// JPEG has no transparency, use a white background instead
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Over, nil)
+
+	buf := new(bytes.Buffer)
+	if err = jpeg.Encode(buf, dst, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, fmt.Errorf("encoding image: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
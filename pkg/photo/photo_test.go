@@ -0,0 +1,156 @@
+package photo
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/emersion/go-vcard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/config"
+)
+
+func getTestPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := range width {
+		for y := range height {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), A: 0xff}) //#nosec:G115 // Overflow is fine for test colors
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, png.Encode(buf, img))
+
+	return buf.Bytes()
+}
+
+func getPhotoCard(value string, params vcard.Params) vcard.Card {
+	return vcard.Card{vcard.FieldPhoto: []*vcard.Field{{Value: value, Params: params}}}
+}
+
+func assertSize(t *testing.T, p *Photo, width, height int) {
+	t.Helper()
+
+	require.NotNil(t, p)
+	assert.Equal(t, ContentType, p.ContentType)
+
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(p.Data))
+	require.NoError(t, err)
+	assert.Equal(t, width, cfg.Width)
+	assert.Equal(t, height, cfg.Height)
+}
+
+func TestGetInline(t *testing.T) {
+	l, err := New(config.PhotoConfig{Size: 50})
+	require.NoError(t, err)
+
+	raw := base64.StdEncoding.EncodeToString(getTestPNG(t, 200, 100))
+
+	// vCard 4.0 data URI
+	p, err := l.Get(getPhotoCard("data:image/png;base64,"+raw, nil))
+	require.NoError(t, err)
+	assertSize(t, p, 50, 25)
+	assert.Empty(t, p.URL)
+
+	// vCard 3.0 base64 encoding with folded lines
+	p, err = l.Get(getPhotoCard(raw[:20]+" "+raw[20:], vcard.Params{"ENCODING": {"b"}, "TYPE": {"PNG"}}))
+	require.NoError(t, err)
+	assertSize(t, p, 50, 25)
+
+	// Small images are not scaled up
+	p, err = l.Get(getPhotoCard("data:image/png;base64,"+base64.StdEncoding.EncodeToString(getTestPNG(t, 10, 20)), nil))
+	require.NoError(t, err)
+	assertSize(t, p, 10, 20)
+
+	for _, value := range []string{
+		"data:image/png," + raw,
+		"data:image/png;base64,not base64",
+		"data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte("no image")),
+		"ftp://example.com/photo.png",
+	} {
+		_, err = l.Get(getPhotoCard(value, nil))
+		assert.Error(t, err, value)
+	}
+
+	p, err = l.Get(vcard.Card{})
+	require.NoError(t, err)
+	assert.Nil(t, p)
+}
+
+func TestGetURL(t *testing.T) {
+	var requests atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		switch r.URL.Path {
+		case "/photo.png":
+			_, _ = w.Write(getTestPNG(t, 100, 200))
+		case "/large.png":
+			_, _ = w.Write(make([]byte, 2048))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	l, err := New(config.PhotoConfig{})
+	require.NoError(t, err)
+
+	// Fetching is disabled by default
+	p, err := l.Get(getPhotoCard(srv.URL+"/photo.png", nil))
+	require.NoError(t, err)
+	assert.Equal(t, srv.URL+"/photo.png", p.URL)
+	assert.Empty(t, p.Data)
+	assert.Equal(t, int64(0), requests.Load())
+
+	l, err = New(config.PhotoConfig{Fetch: true, MaxBytes: 1024})
+	require.NoError(t, err)
+
+	for range 2 {
+		p, err = l.Get(getPhotoCard(srv.URL+"/photo.png", nil))
+		require.NoError(t, err)
+		assertSize(t, p, DefaultSize/2, DefaultSize)
+		assert.Equal(t, srv.URL+"/photo.png", p.URL)
+	}
+	assert.Equal(t, int64(1), requests.Load(), "photo must be cached")
+
+	_, err = l.Get(getPhotoCard(srv.URL+"/large.png", nil))
+	assert.ErrorIs(t, err, ErrTooLarge)
+
+	requests.Store(0)
+	for range 2 {
+		_, err = l.Get(getPhotoCard(srv.URL+"/missing.png", nil))
+		assert.Error(t, err)
+	}
+	assert.Equal(t, int64(1), requests.Load(), "failure must be cached")
+}
+
+func TestNilLoader(t *testing.T) {
+	var l *Loader
+
+	p, err := l.Get(getPhotoCard("https://example.com/photo.png", nil))
+	require.NoError(t, err)
+	assert.Nil(t, p)
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	for _, cfg := range []config.PhotoConfig{
+		{MaxBytes: -1},
+		{Size: -1},
+		{Timeout: -1},
+	} {
+		_, err := New(cfg)
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,229 @@
+// Package report contains a data-quality report listing contacts
+// whose birthdays cannot be notified about properly so they can be
+// fixed in the address book
+package report
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/emersion/go-vcard"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/dateutil"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/dedup"
+)
+
+// Kinds of issues found in the contacts
+const (
+	KindDuplicate             Kind = "duplicate"
+	KindFutureBirthday        Kind = "future-birthday"
+	KindImplausibleAge        Kind = "implausible-age"
+	KindMissingName           Kind = "missing-name"
+	KindUnparseableBirthday   Kind = "unparseable-birthday"
+	KindUnschedulableBirthday Kind = "unschedulable-birthday"
+)
+
+const (
+	// maxPlausibleAge is the age above which a birthday is reported as
+	// likely being a typo
+	maxPlausibleAge = 120
+
+	tablePadding = 2
+	unnamed      = "(unnamed)"
+)
+
+type (
+	// Contact is a contact together with the account and the address
+	// book it was fetched from
+	Contact struct {
+		Account     string
+		AddressBook string
+		Card        vcard.Card
+	}
+
+	// Issue describes a single problem found in a contact
+	Issue struct {
+		Account     string `json:"account,omitempty"`
+		AddressBook string `json:"addressBook"`
+		Kind        Kind   `json:"kind"`
+		Message     string `json:"message"`
+		Name        string `json:"name"`
+		UID         string `json:"uid,omitempty"`
+	}
+
+	// Kind classifies an Issue
+	Kind string
+
+	// Report contains the issues found in the contacts at the time of
+	// generation
+	Report struct {
+		Generated time.Time `json:"generated"`
+		Issues    []Issue   `json:"issues"`
+	}
+)
+
+// Generate checks all contacts having a birthday and collects the
+// issues found in them
+func Generate(contacts []Contact, now time.Time) Report {
+	r := Report{Generated: now, Issues: []Issue{}}
+
+	// Contacts with a usable birthday as seen by the deduplication
+	var (
+		parsed  []Contact
+		entries []dedup.Entry
+	)
+
+	for _, c := range contacts {
+		field := c.Card.Get(vcard.FieldBirthday)
+		if field == nil {
+			continue
+		}
+
+		if msg := checkName(c.Card); msg != "" {
+			r.add(c, KindMissingName, msg)
+		}
+
+		birthday, err := dateutil.Parse(field)
+		switch {
+		case errors.Is(err, dateutil.ErrNotSchedulable):
+			r.add(c, KindUnschedulableBirthday, err.Error())
+
+		case err != nil:
+			r.add(c, KindUnparseableBirthday, err.Error())
+
+		default:
+			r.checkBirthday(c, birthday, now)
+
+			parsed = append(parsed, c)
+			entries = append(entries, dedup.Entry{Account: c.Account, AddressBook: c.AddressBook, Birthday: birthday, Card: c.Card})
+		}
+	}
+
+	r.addDuplicates(parsed, dedup.GroupEntries(entries))
+
+	sort.SliceStable(r.Issues, func(i, j int) bool {
+		if r.Issues[i].Name != r.Issues[j].Name {
+			return r.Issues[i].Name < r.Issues[j].Name
+		}
+		return r.Issues[i].Kind < r.Issues[j].Kind
+	})
+
+	return r
+}
+
+// WriteText writes the report as human readable table
+func (r Report) WriteText(w io.Writer) error {
+	if len(r.Issues) == 0 {
+		if _, err := fmt.Fprintln(w, "No issues found."); err != nil {
+			return fmt.Errorf("writing report: %w", err)
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, tablePadding, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tACCOUNT\tADDRESS BOOK\tKIND\tMESSAGE")
+	for _, i := range r.Issues {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", i.Name, i.Account, i.AddressBook, i.Kind, i.Message)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("writing report: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Report) add(c Contact, kind Kind, message string) {
+	r.Issues = append(r.Issues, Issue{
+		Account:     c.Account,
+		AddressBook: c.AddressBook,
+		Kind:        kind,
+		Message:     message,
+		Name:        displayName(c.Card),
+		UID:         c.Card.Value(vcard.FieldUID),
+	})
+}
+
+// addDuplicates reports the first contact of each group of contacts
+// describing the same person
+func (r *Report) addDuplicates(contacts []Contact, groups [][]int) {
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+
+		books := make([]string, 0, len(group))
+		for _, idx := range group {
+			books = append(books, contacts[idx].source())
+		}
+
+		r.add(contacts[group[0]], KindDuplicate, fmt.Sprintf(
+			"contact exists %d times (address books: %s)",
+			len(group), strings.Join(books, ", "),
+		))
+	}
+}
+
+// source describes where the contact was fetched from: The address
+// book prefixed with the account if known
+func (c Contact) source() string {
+	if c.Account == "" {
+		return c.AddressBook
+	}
+
+	return c.Account + "/" + c.AddressBook
+}
+
+func (r *Report) checkBirthday(c Contact, birthday dateutil.Birthday, now time.Time) {
+	if !birthday.HasYear() {
+		return
+	}
+
+	if birthday.Time().After(now) {
+		r.add(c, KindFutureBirthday, fmt.Sprintf("birthday %s is in the future", birthday))
+		return
+	}
+
+	if age, _ := birthday.Age(now); age > maxPlausibleAge {
+		r.add(c, KindImplausibleAge, fmt.Sprintf("birthday %s yields an age of %d", birthday, age))
+	}
+}
+
+// checkName returns a message describing what is missing for the
+// notifiers to display the name of the contact
+func checkName(card vcard.Card) string {
+	var missing []string
+
+	if card.Name() == nil {
+		missing = append(missing, vcard.FieldName)
+	}
+
+	if card.PreferredValue(vcard.FieldFormattedName) == "" {
+		missing = append(missing, vcard.FieldFormattedName)
+	}
+
+	if len(missing) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("contact has no %s", strings.Join(missing, " / "))
+}
+
+func displayName(card vcard.Card) string {
+	if fn := card.PreferredValue(vcard.FieldFormattedName); fn != "" {
+		return fn
+	}
+
+	if n := card.Name(); n != nil {
+		if name := strings.TrimSpace(strings.Join([]string{n.GivenName, n.FamilyName}, " ")); name != "" {
+			return name
+		}
+	}
+
+	return unnamed
+}
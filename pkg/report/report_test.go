@@ -0,0 +1,86 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-vcard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestContact(t *testing.T, addressBook, content string) Contact {
+	t.Helper()
+
+	c, err := vcard.NewDecoder(strings.NewReader("BEGIN:VCARD\nVERSION:4.0\n" + content + "END:VCARD")).Decode()
+	require.NoError(t, err)
+
+	return Contact{AddressBook: addressBook, Card: c}
+}
+
+func TestGenerate(t *testing.T) {
+	now := time.Date(2026, 3, 13, 12, 0, 0, 0, time.Local)
+
+	r := Generate([]Contact{
+		getTestContact(t, "Contacts", "N:Bloggs;Joe;;;\nFN:Joe Bloggs\nBDAY:19900313\n"),
+		getTestContact(t, "Contacts", "N:Doe;Jane;;;\nFN:Jane Doe\nBDAY:not a date\n"),
+		getTestContact(t, "Contacts", "FN:Max Mustermann\nBDAY:--03\n"),
+		getTestContact(t, "Contacts", "N:Future;Fiona;;;\nFN:Fiona Future\nBDAY:20300101\n"),
+		getTestContact(t, "Contacts", "N:Old;Otto;;;\nFN:Otto Old\nBDAY:18500101\n"),
+		getTestContact(t, "Contacts", "N:Twin;Tom;;;\nFN:Tom Twin\nUID:tom\nBDAY:19800101\n"),
+		getTestContact(t, "Work", "N:Twin;Tom;;;\nFN:Tom Twin\nUID:tom\nBDAY:19800101\n"),
+		getTestContact(t, "Work", "N:Nobody;No;;;\nFN:No Birthday\n"),
+	}, now)
+
+	kinds := make(map[string][]Kind)
+	for _, i := range r.Issues {
+		kinds[i.Name] = append(kinds[i.Name], i.Kind)
+	}
+
+	assert.Equal(t, map[string][]Kind{
+		"Fiona Future":   {KindFutureBirthday},
+		"Jane Doe":       {KindUnparseableBirthday},
+		"Max Mustermann": {KindMissingName, KindUnschedulableBirthday},
+		"Otto Old":       {KindImplausibleAge},
+		"Tom Twin":       {KindDuplicate},
+	}, kinds)
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, r.WriteText(buf))
+	assert.Contains(t, buf.String(), "address books: Contacts, Work")
+}
+
+func TestGenerateDuplicatesLikeDeduplication(t *testing.T) {
+	r := Generate([]Contact{
+		getTestContact(t, "Contacts", "N:Doe;Jane;;;\nFN:Jane  Doe\nUID:jane\nBDAY:1990-03-13\n"),
+		getTestContact(t, "Work", "N:Doe;Jane;;;\nFN:jane doe\nBDAY:19900313\n"),
+		getTestContact(t, "Family", "N:Doe;Jane;;;\nFN:Janie\nUID:jane\nBDAY:19900313\n"),
+		getTestContact(t, "Work", "N:Doe;John;;;\nFN:John Doe\nBDAY:19900313\n"),
+	}, time.Date(2026, 3, 13, 12, 0, 0, 0, time.Local))
+
+	// Same normalized name and birthday (in different notations) and
+	// transitively the contact sharing the UID
+	require.Len(t, r.Issues, 1)
+	assert.Equal(t, KindDuplicate, r.Issues[0].Kind)
+	assert.Equal(t, "contact exists 3 times (address books: Contacts, Work, Family)", r.Issues[0].Message)
+}
+
+func TestGenerateAccounts(t *testing.T) {
+	alice := getTestContact(t, "Contacts", "N:Twin;Tom;;;\nFN:Tom Twin\nUID:tom\nBDAY:19800101\n")
+	alice.Account = "alice"
+	bob := alice
+	bob.Account = "bob"
+
+	r := Generate([]Contact{alice, bob}, time.Now())
+	require.Len(t, r.Issues, 1)
+	assert.Equal(t, "alice", r.Issues[0].Account)
+	assert.Equal(t, "contact exists 2 times (address books: alice/Contacts, bob/Contacts)", r.Issues[0].Message)
+}
+
+func TestWriteTextEmpty(t *testing.T) {
+	buf := new(bytes.Buffer)
+	require.NoError(t, Generate(nil, time.Now()).WriteText(buf))
+	assert.Equal(t, "No issues found.\n", buf.String())
+}
@@ -0,0 +1,257 @@
+// Package routing contains the logic to decide which notifiers are
+// used to notify about the birthday of a contact
+package routing
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-vcard"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/advance"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/config"
+)
+
+type (
+	// Router matches contacts against the configured routes
+	Router struct {
+		defaultTarget Target
+		notifiers     []config.NotifierConfig
+		routes        []route
+	}
+
+	// EscalationStep describes the notifiers to notify additionally if
+	// the notification has not been acknowledged after some time
+	EscalationStep struct {
+		// After is the time since the initial notification
+		After time.Duration
+		// Notifiers contains the indexes of the notifiers in the
+		// configuration file
+		Notifiers []int
+	}
+
+	// Target describes which notifiers to send the notification to and
+	// when to notify relative to the birthday
+	Target struct {
+		// Advance contains the rules when to notify additionally to the
+		// birthday itself
+		Advance []advance.Rule
+		// Escalation contains the steps to escalate unacknowledged
+		// notifications, ordered by their time
+		Escalation []EscalationStep
+		// Notifiers contains the indexes of the notifiers in the
+		// configuration file
+		Notifiers []int
+	}
+
+	route struct {
+		match     config.RouteMatchConfig
+		nameRegex *regexp.Regexp
+		target    Target
+	}
+)
+
+// New validates the routes in the given configuration and creates
+// a new Router from them
+func New(configFile config.File) (*Router, error) {
+	r := &Router{
+		defaultTarget: Target{
+			Advance: configFile.NotifyDaysInAdvance,
+		},
+		notifiers: configFile.Notifiers,
+	}
+
+	for i := range configFile.Notifiers {
+		if configFile.Notifiers[i].IsEnabled() {
+			r.defaultTarget.Notifiers = append(r.defaultTarget.Notifiers, i)
+		}
+	}
+
+	for i, routeCfg := range configFile.Routes {
+		var err error
+
+		rt := route{
+			match: routeCfg.Match,
+			target: Target{
+				Advance: routeCfg.NotifyDaysInAdvance,
+			},
+		}
+
+		if rt.target.Advance == nil {
+			rt.target.Advance = configFile.NotifyDaysInAdvance
+		}
+
+		if routeCfg.Match.NameRegex != "" {
+			if rt.nameRegex, err = regexp.Compile(routeCfg.Match.NameRegex); err != nil {
+				return nil, fmt.Errorf("route %d: compiling nameRegex: %w", i, err)
+			}
+		}
+
+		if len(routeCfg.Notifiers) == 0 {
+			return nil, fmt.Errorf("route %d: no notifiers given", i)
+		}
+
+		if rt.target.Notifiers, err = r.ResolveNotifiers(routeCfg.Notifiers); err != nil {
+			return nil, fmt.Errorf("route %d: %w", i, err)
+		}
+
+		if rt.target.Escalation, err = r.resolveEscalation(configFile, routeCfg.Escalation, rt.target.Notifiers); err != nil {
+			return nil, fmt.Errorf("route %d: %w", i, err)
+		}
+
+		r.routes = append(r.routes, rt)
+	}
+
+	return r, nil
+}
+
+// Resolve returns the Target of the first route matching the contact
+// or the default Target (all notifiers, global days in advance) in
+// case no route matches
+func (r Router) Resolve(contact vcard.Card, account, addressBook string) Target {
+	for _, rt := range r.routes {
+		if rt.matches(contact, account, addressBook) {
+			return rt.target
+		}
+	}
+
+	return r.defaultTarget
+}
+
+// ResolveNotifiers translates the notifier references (either the
+// name of a notifier or a type to select all notifiers of that type)
+// into the indexes of the enabled notifiers in the configuration file
+func (r Router) ResolveNotifiers(refs []string) (idxs []int, err error) {
+	for _, ref := range refs {
+		var found bool
+
+		for i := range r.notifiers {
+			if r.notifiers[i].Name != ref && r.notifiers[i].Type != ref {
+				continue
+			}
+
+			found = true
+			if r.notifiers[i].IsEnabled() && !slices.Contains(idxs, i) {
+				idxs = append(idxs, i)
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("notifier %q does not exist", ref)
+		}
+	}
+
+	return idxs, nil
+}
+
+// WithNotifiers returns a copy of the Target notifying the given
+// notifiers instead: As follow-ups are tracked per notifier, they are
+// removed from the escalation steps and steps left without notifiers
+// are dropped
+func (t Target) WithNotifiers(notifiers []int) Target {
+	t.Notifiers = notifiers
+
+	var escalation []EscalationStep
+	for _, step := range t.Escalation {
+		remaining := slices.DeleteFunc(slices.Clone(step.Notifiers), func(idx int) bool { return slices.Contains(notifiers, idx) })
+		if len(remaining) > 0 {
+			escalation = append(escalation, EscalationStep{After: step.After, Notifiers: remaining})
+		}
+	}
+	t.Escalation = escalation
+
+	return t
+}
+
+// resolveEscalation validates the escalation steps and resolves their
+// notifiers which must not overlap with the notifiers of the route
+func (r Router) resolveEscalation(configFile config.File, steps []config.EscalationStepConfig, routeNotifiers []int) (escalation []EscalationStep, err error) {
+	if len(steps) > 0 && configFile.Actions == nil {
+		return nil, fmt.Errorf("escalation requires actions to be configured")
+	}
+
+	for i, step := range steps {
+		switch {
+		case step.After <= 0:
+			return nil, fmt.Errorf("escalation step %d: after must be positive", i)
+
+		case i > 0 && step.After <= steps[i-1].After:
+			return nil, fmt.Errorf("escalation step %d: after must be greater than the one of the previous step", i)
+
+		case len(step.Notifiers) == 0:
+			return nil, fmt.Errorf("escalation step %d: no notifiers given", i)
+		}
+
+		notifiers, err := r.ResolveNotifiers(step.Notifiers)
+		if err != nil {
+			return nil, fmt.Errorf("escalation step %d: %w", i, err)
+		}
+
+		// Follow-ups are tracked per notifier (shared with snoozes of the
+		// initial notification), each notifier can only be part of the
+		// route itself or one step
+		if slices.ContainsFunc(notifiers, func(idx int) bool { return slices.Contains(routeNotifiers, idx) }) {
+			return nil, fmt.Errorf("escalation step %d: notifier is already notified by the route", i)
+		}
+
+		for _, prev := range escalation {
+			if slices.ContainsFunc(notifiers, func(idx int) bool { return slices.Contains(prev.Notifiers, idx) }) {
+				return nil, fmt.Errorf("escalation step %d: notifier is already used in a previous step", i)
+			}
+		}
+
+		escalation = append(escalation, EscalationStep{After: step.After, Notifiers: notifiers})
+	}
+
+	return escalation, nil
+}
+
+func (rt route) matches(contact vcard.Card, account, addressBook string) bool {
+	if len(rt.match.Accounts) > 0 && !containsFold(rt.match.Accounts, account) {
+		return false
+	}
+
+	if len(rt.match.AddressBooks) > 0 && !containsFold(rt.match.AddressBooks, addressBook) {
+		return false
+	}
+
+	if len(rt.match.Categories) > 0 && !slices.ContainsFunc(contact.Categories(), func(c string) bool {
+		return containsFold(rt.match.Categories, strings.TrimSpace(c))
+	}) {
+		return false
+	}
+
+	if len(rt.match.Organizations) > 0 && !slices.ContainsFunc(contact.Values(vcard.FieldOrganization), func(org string) bool {
+		name, _, _ := strings.Cut(org, ";")
+		return containsFold(rt.match.Organizations, org) || containsFold(rt.match.Organizations, name)
+	}) {
+		return false
+	}
+
+	if rt.nameRegex != nil && !slices.ContainsFunc(contactNames(contact), rt.nameRegex.MatchString) {
+		return false
+	}
+
+	return true
+}
+
+func containsFold(haystack []string, needle string) bool {
+	return slices.ContainsFunc(haystack, func(s string) bool { return strings.EqualFold(s, needle) })
+}
+
+// contactNames returns the formatted names and the combination of
+// given and family name to match the name regex against
+func contactNames(contact vcard.Card) (names []string) {
+	for _, fn := range contact.FormattedNames() {
+		names = append(names, fn.Value)
+	}
+
+	if n := contact.Name(); n != nil {
+		names = append(names, strings.TrimSpace(n.GivenName+" "+n.FamilyName))
+	}
+
+	return names
+}
@@ -0,0 +1,219 @@
+package routing
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-vcard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/advance"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/config"
+)
+
+func getTestVCard(t *testing.T, extra string) vcard.Card {
+	t.Helper()
+
+	c, err := vcard.NewDecoder(strings.NewReader("BEGIN:VCARD\nVERSION:4.0\nN:Bloggs;Joe;;;\nFN:Joe Bloggs\n" + extra + "END:VCARD")).Decode()
+	require.NoError(t, err)
+
+	return c
+}
+
+func TestResolve(t *testing.T) {
+	var (
+		oneDay    = advance.MustParse("1")
+		threeDays = advance.MustParse("3")
+	)
+
+	r, err := New(config.File{
+		NotifyDaysInAdvance: []advance.Rule{oneDay},
+		Notifiers: []config.NotifierConfig{
+			{Name: "slack-team", Type: "slack"},
+			{Name: "pushover-0", Type: "pushover"},
+			{Name: "log-2", Type: "log"},
+			{Name: "slack-disabled", Type: "slack", Enabled: func(v bool) *bool { return &v }(false)},
+		},
+		Routes: []config.RouteConfig{
+			{
+				Match:               config.RouteMatchConfig{Categories: []string{"colleagues"}, Organizations: []string{"ACME"}},
+				Notifiers:           []string{"slack"},
+				NotifyDaysInAdvance: []advance.Rule{threeDays},
+			},
+			{
+				Match:     config.RouteMatchConfig{AddressBooks: []string{"Family"}},
+				Notifiers: []string{"pushover"},
+			},
+			{
+				Match:     config.RouteMatchConfig{NameRegex: `^Jane `},
+				Notifiers: []string{"log-2", "pushover"},
+			},
+			{
+				Match:     config.RouteMatchConfig{Accounts: []string{"Partner"}},
+				Notifiers: []string{"log"},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	// Colleague working for ACME
+	assert.Equal(t,
+		Target{Advance: []advance.Rule{threeDays}, Notifiers: []int{0}},
+		r.Resolve(getTestVCard(t, "CATEGORIES:friends,colleagues\nORG:acme;Sales\n"), "me", "Contacts"))
+
+	// Colleague not working for ACME is not matched by the first route
+	assert.Equal(t,
+		Target{Advance: []advance.Rule{oneDay}, Notifiers: []int{1}},
+		r.Resolve(getTestVCard(t, "CATEGORIES:colleagues\nORG:Other\n"), "me", "Family"))
+
+	// Name regex
+	assert.Equal(t,
+		Target{Advance: []advance.Rule{oneDay}, Notifiers: []int{2, 1}},
+		r.Resolve(getTestVCard(t, "FN:Jane Doe\n"), "me", "Contacts"))
+
+	// Account
+	assert.Equal(t,
+		Target{Advance: []advance.Rule{oneDay}, Notifiers: []int{2}},
+		r.Resolve(getTestVCard(t, ""), "partner", "Contacts"))
+
+	// Unmatched contacts go to all notifiers
+	assert.Equal(t,
+		Target{Advance: []advance.Rule{oneDay}, Notifiers: []int{0, 1, 2}},
+		r.Resolve(getTestVCard(t, ""), "me", "Contacts"))
+}
+
+func TestResolveNotifiers(t *testing.T) {
+	r, err := New(config.File{
+		Notifiers: []config.NotifierConfig{
+			{Name: "slack-team", Type: "slack"},
+			{Name: "slack-family", Type: "slack"},
+			{Name: "slack-disabled", Type: "slack", Enabled: func(v bool) *bool { return &v }(false)},
+		},
+	})
+	require.NoError(t, err)
+
+	idxs, err := r.ResolveNotifiers([]string{"slack-family"})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1}, idxs)
+
+	idxs, err = r.ResolveNotifiers([]string{"slack", "slack-team"})
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1}, idxs, "type must select all enabled notifiers of that type")
+
+	idxs, err = r.ResolveNotifiers([]string{"slack-disabled"})
+	require.NoError(t, err)
+	assert.Empty(t, idxs)
+
+	_, err = r.ResolveNotifiers([]string{"pushover"})
+	assert.Error(t, err)
+}
+
+func TestNewValidation(t *testing.T) {
+	_, err := New(config.File{
+		Notifiers: []config.NotifierConfig{{Type: "log"}},
+		Routes:    []config.RouteConfig{{Notifiers: []string{"slack"}}},
+	})
+	assert.Error(t, err, "unknown notifier")
+
+	_, err = New(config.File{
+		Notifiers: []config.NotifierConfig{{Type: "log"}},
+		Routes:    []config.RouteConfig{{Match: config.RouteMatchConfig{NameRegex: "("}, Notifiers: []string{"log"}}},
+	})
+	assert.Error(t, err, "invalid regex")
+
+	_, err = New(config.File{
+		Notifiers: []config.NotifierConfig{{Type: "log"}},
+		Routes:    []config.RouteConfig{{}},
+	})
+	assert.Error(t, err, "no notifiers")
+}
+
+func TestEscalation(t *testing.T) {
+	notifiers := []config.NotifierConfig{
+		{Name: "slack", Type: "slack"},
+		{Name: "pushover-urgent", Type: "pushover"},
+		{Name: "email", Type: "exec"},
+	}
+
+	r, err := New(config.File{
+		Actions:   &config.ActionsConfig{},
+		Notifiers: notifiers,
+		Routes: []config.RouteConfig{{
+			Escalation: []config.EscalationStepConfig{
+				{After: 4 * time.Hour, Notifiers: []string{"pushover-urgent"}},
+				{After: 8 * time.Hour, Notifiers: []string{"exec"}},
+			},
+			Match:     config.RouteMatchConfig{Categories: []string{"family"}},
+			Notifiers: []string{"slack"},
+		}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []EscalationStep{
+		{After: 4 * time.Hour, Notifiers: []int{1}},
+		{After: 8 * time.Hour, Notifiers: []int{2}},
+	}, r.Resolve(getTestVCard(t, "CATEGORIES:family\n"), "me", "").Escalation)
+	assert.Empty(t, r.Resolve(getTestVCard(t, ""), "me", "").Escalation)
+
+	for name, steps := range map[string][]config.EscalationStepConfig{
+		"no after":         {{Notifiers: []string{"email"}}},
+		"unordered":        {{After: time.Hour, Notifiers: []string{"email"}}, {After: time.Hour, Notifiers: []string{"pushover"}}},
+		"no notifiers":     {{After: time.Hour}},
+		"unknown notifier": {{After: time.Hour, Notifiers: []string{"sms"}}},
+		"duplicate":        {{After: time.Hour, Notifiers: []string{"email"}}, {After: 2 * time.Hour, Notifiers: []string{"exec"}}},
+		"route notifier":   {{After: time.Hour, Notifiers: []string{"slack"}}},
+		"route type":       {{After: time.Hour, Notifiers: []string{"email"}}, {After: 2 * time.Hour, Notifiers: []string{"slack"}}},
+	} {
+		_, err = New(config.File{
+			Actions:   &config.ActionsConfig{},
+			Notifiers: notifiers,
+			Routes:    []config.RouteConfig{{Escalation: steps, Notifiers: []string{"slack"}}},
+		})
+		assert.Error(t, err, name)
+	}
+
+	// Follow-ups share their state with snoozes of the initial
+	// notification, the route notifiers must not be escalated to
+	_, err = New(config.File{
+		Actions:   &config.ActionsConfig{},
+		Notifiers: notifiers,
+		Routes: []config.RouteConfig{{
+			Escalation: []config.EscalationStepConfig{{After: time.Hour, Notifiers: []string{"pushover"}}},
+			Notifiers:  []string{"slack", "pushover-urgent"},
+		}},
+	})
+	assert.ErrorContains(t, err, "already notified by the route")
+
+	_, err = New(config.File{
+		Notifiers: notifiers,
+		Routes: []config.RouteConfig{{
+			Escalation: []config.EscalationStepConfig{{After: time.Hour, Notifiers: []string{"email"}}},
+			Notifiers:  []string{"slack"},
+		}},
+	})
+	assert.Error(t, err, "escalation without actions")
+}
+
+func TestTargetWithNotifiers(t *testing.T) {
+	target := Target{
+		Escalation: []EscalationStep{
+			{After: 4 * time.Hour, Notifiers: []int{1}},
+			{After: 8 * time.Hour, Notifiers: []int{2, 3}},
+		},
+		Notifiers: []int{0},
+	}
+
+	overridden := target.WithNotifiers([]int{1, 2})
+	assert.Equal(t, []int{1, 2}, overridden.Notifiers)
+	assert.Equal(t, []EscalationStep{{After: 8 * time.Hour, Notifiers: []int{3}}}, overridden.Escalation)
+
+	// The original target (shared by all contacts of the route) stays
+	// untouched
+	assert.Equal(t, []int{0}, target.Notifiers)
+	assert.Equal(t, []int{2, 3}, target.Escalation[1].Notifiers)
+
+	assert.Empty(t, target.WithNotifiers([]int{1, 2, 3}).Escalation)
+	assert.Equal(t, target.Escalation, target.WithNotifiers([]int{4}).Escalation)
+}
@@ -0,0 +1,141 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/config"
+)
+
+// maxRestrictionSteps limits the number of windows to skip when
+// searching for the next allowed time to prevent endless loops in
+// case of a configuration blocking every single day
+const maxRestrictionSteps = 1000
+
+type (
+	// Restrictions describes when deliveries must not happen and
+	// calculates when they are allowed again
+	Restrictions struct {
+		blackoutDates []blackoutDate
+		quietHours    *quietHours
+	}
+
+	blackoutDate struct {
+		day   int
+		month time.Month
+		year  int // 0 = every year
+	}
+
+	quietHours struct {
+		end   time.Duration // Offset since midnight
+		start time.Duration // Offset since midnight
+	}
+)
+
+// NewRestrictions parses the quiet hours and blackout dates. Blackout
+// dates are either given as `2006-01-02` for a specific date or as
+// `01-02` for a date recurring every year.
+func NewRestrictions(qh *config.QuietHoursConfig, blackoutDates []string) (r Restrictions, err error) {
+	if qh != nil {
+		r.quietHours = &quietHours{}
+
+		if r.quietHours.start, err = parseTimeOfDay(qh.Start); err != nil {
+			return r, fmt.Errorf("parsing quiet hours start: %w", err)
+		}
+
+		if r.quietHours.end, err = parseTimeOfDay(qh.End); err != nil {
+			return r, fmt.Errorf("parsing quiet hours end: %w", err)
+		}
+
+		if r.quietHours.start == r.quietHours.end {
+			return r, fmt.Errorf("quiet hours start and end must differ")
+		}
+	}
+
+	for _, raw := range blackoutDates {
+		if d, err := time.Parse(time.DateOnly, raw); err == nil {
+			r.blackoutDates = append(r.blackoutDates, blackoutDate{day: d.Day(), month: d.Month(), year: d.Year()})
+			continue
+		}
+
+		d, err := time.Parse("01-02", raw)
+		if err != nil {
+			return r, fmt.Errorf("blackout date %q is neither YYYY-MM-DD nor MM-DD", raw)
+		}
+		r.blackoutDates = append(r.blackoutDates, blackoutDate{day: d.Day(), month: d.Month()})
+	}
+
+	return r, nil
+}
+
+// NextAllowed returns the given time if it is not restricted or the
+// end of the restriction window the time falls into
+func (r Restrictions) NextAllowed(t time.Time) time.Time {
+	for range maxRestrictionSteps {
+		switch {
+		case r.isBlackout(t):
+			// Move to the start of the next day and check again
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+
+		case r.quietHours != nil && r.quietHours.contains(t):
+			t = r.quietHours.endAfter(t)
+
+		default:
+			return t
+		}
+	}
+
+	return t
+}
+
+func (r Restrictions) isBlackout(t time.Time) bool {
+	for _, d := range r.blackoutDates {
+		if d.day == t.Day() && d.month == t.Month() && (d.year == 0 || d.year == t.Year()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (q quietHours) contains(t time.Time) bool {
+	offset := sinceMidnight(t)
+
+	if q.start < q.end {
+		// Window within one day: 12:00 - 14:00
+		return offset >= q.start && offset < q.end
+	}
+
+	// Window spanning midnight: 22:00 - 07:00
+	return offset >= q.start || offset < q.end
+}
+
+// endAfter returns the end of the quiet hours window t is within
+func (q quietHours) endAfter(t time.Time) time.Time {
+	var (
+		hour   = int(q.end / time.Hour)
+		minute = int(q.end % time.Hour / time.Minute)
+	)
+
+	end := time.Date(t.Year(), t.Month(), t.Day(), hour, minute, 0, 0, t.Location())
+	if !end.After(t) {
+		end = time.Date(t.Year(), t.Month(), t.Day()+1, hour, minute, 0, 0, t.Location())
+	}
+
+	return end
+}
+
+func parseTimeOfDay(raw string) (time.Duration, error) {
+	t, err := time.Parse("15:04", raw)
+	if err != nil {
+		return 0, fmt.Errorf("parsing %q as HH:MM: %w", raw, err)
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func sinceMidnight(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour +
+		time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second
+}
@@ -0,0 +1,172 @@
+// Package scheduler contains a scheduler holding deliveries which
+// must not be executed immediately until their time has come. Pending
+// deliveries are persisted to survive restarts.
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Kinds of deliveries
+const (
+	KindGreeting     = "greeting"
+	KindNotification = "notification"
+)
+
+const stateFileMode = 0o600
+
+type (
+	// Delivery describes a notification or greeting to be sent at a
+	// given time. It only references the contact and the notifier which
+	// are looked up when it is delivered so it can be persisted.
+	Delivery struct {
+		At         time.Time `json:"at"`
+		Contact    string    `json:"contact"`
+		Kind       string    `json:"kind"`
+		Notifier   string    `json:"notifier,omitempty"`
+		Occurrence time.Time `json:"occurrence"`
+	}
+
+	// Scheduler holds the deliveries until their time has come and
+	// passes them to the deliver function then
+	Scheduler struct {
+		deliver   func(Delivery)
+		stateFile string
+
+		lock    sync.Mutex
+		nextID  uint64
+		pending map[uint64]Delivery
+	}
+)
+
+// New creates a new Scheduler passing due deliveries to the deliver
+// function. If a state file is given, the pending deliveries are
+// stored in it and the ones stored before are loaded: They are not
+// scheduled before Resume is called.
+func New(stateFile string, deliver func(Delivery)) (*Scheduler, error) {
+	s := &Scheduler{
+		deliver:   deliver,
+		stateFile: stateFile,
+		pending:   make(map[uint64]Delivery),
+	}
+
+	if err := s.load(); err != nil {
+		return nil, fmt.Errorf("loading state: %w", err)
+	}
+
+	return s, nil
+}
+
+// Resume schedules the deliveries loaded from the state file,
+// deliveries whose time has passed while not running are delivered
+// immediately
+func (s *Scheduler) Resume() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for id, d := range s.pending {
+		s.start(id, d)
+	}
+}
+
+// Schedule stores the delivery and passes it to the deliver function
+// in its own go-routine at Delivery.At. If the time is not in the
+// future it is delivered immediately.
+func (s *Scheduler) Schedule(d Delivery) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	id := s.nextID
+	s.nextID++
+	s.pending[id] = d
+
+	if err := s.save(); err != nil {
+		logrus.WithError(err).Error("saving scheduler state")
+	}
+
+	s.start(id, d)
+}
+
+// start creates the timer for the pending delivery, the lock MUST be
+// held by the caller
+func (s *Scheduler) start(id uint64, d Delivery) {
+	time.AfterFunc(time.Until(d.At), func() {
+		s.lock.Lock()
+		delete(s.pending, id)
+		if err := s.save(); err != nil {
+			logrus.WithError(err).Error("saving scheduler state")
+		}
+		s.lock.Unlock()
+
+		s.deliver(d)
+	})
+}
+
+// load reads the pending deliveries from the state file, the lock MUST
+// be held by the caller or the Scheduler not yet be in use
+func (s *Scheduler) load() error {
+	if s.stateFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.stateFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("reading state file: %w", err)
+	}
+
+	var deliveries []Delivery
+	if err = json.Unmarshal(data, &deliveries); err != nil {
+		return fmt.Errorf("decoding state file: %w", err)
+	}
+
+	for _, d := range deliveries {
+		s.pending[s.nextID] = d
+		s.nextID++
+	}
+
+	return nil
+}
+
+// save writes the pending deliveries to the state file, the lock MUST
+// be held by the caller
+func (s *Scheduler) save() error {
+	if s.stateFile == "" {
+		return nil
+	}
+
+	deliveries := make([]Delivery, 0, len(s.pending))
+	for _, d := range s.pending {
+		deliveries = append(deliveries, d)
+	}
+	slices.SortFunc(deliveries, func(a, b Delivery) int { return a.At.Compare(b.At) })
+
+	data, err := json.MarshalIndent(deliveries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding state: %w", err)
+	}
+
+	// Write to a temporary file first to not corrupt the state when
+	// failing in the middle of writing
+	tmp := filepath.Join(filepath.Dir(s.stateFile), "."+filepath.Base(s.stateFile)+".tmp")
+	if err = os.WriteFile(tmp, data, stateFileMode); err != nil {
+		return fmt.Errorf("writing state file: %w", err)
+	}
+
+	if err = os.Rename(tmp, s.stateFile); err != nil {
+		return fmt.Errorf("replacing state file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package scheduler
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/config"
+)
+
+func TestNextAllowed(t *testing.T) {
+	r, err := NewRestrictions(
+		&config.QuietHoursConfig{Start: "22:00", End: "07:00"},
+		[]string{"12-24", "2026-12-31"},
+	)
+	require.NoError(t, err)
+
+	at := func(y int, m time.Month, d, h, min int) time.Time {
+		return time.Date(y, m, d, h, min, 0, 0, time.Local)
+	}
+
+	for _, tc := range []struct {
+		In, Out time.Time
+	}{
+		// Midnight is within quiet hours
+		{In: at(2026, 3, 13, 0, 0), Out: at(2026, 3, 13, 7, 0)},
+		// Daytime is allowed
+		{In: at(2026, 3, 13, 12, 30), Out: at(2026, 3, 13, 12, 30)},
+		// Late evening moves to next morning
+		{In: at(2026, 3, 13, 23, 0), Out: at(2026, 3, 14, 7, 0)},
+		// Recurring blackout date moves to the next morning
+		{In: at(2027, 12, 24, 12, 0), Out: at(2027, 12, 25, 7, 0)},
+		// Single blackout date only applies to its year
+		{In: at(2026, 12, 31, 12, 0), Out: at(2027, 1, 1, 7, 0)},
+		{In: at(2027, 12, 31, 12, 0), Out: at(2027, 12, 31, 12, 0)},
+	} {
+		assert.Equal(t, tc.Out, r.NextAllowed(tc.In), tc.In.String())
+	}
+}
+
+func TestNextAllowedDaytimeWindow(t *testing.T) {
+	r, err := NewRestrictions(&config.QuietHoursConfig{Start: "12:00", End: "14:00"}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t,
+		time.Date(2026, 3, 13, 14, 0, 0, 0, time.Local),
+		r.NextAllowed(time.Date(2026, 3, 13, 12, 0, 0, 0, time.Local)))
+
+	assert.Equal(t,
+		time.Date(2026, 3, 13, 0, 0, 0, 0, time.Local),
+		r.NextAllowed(time.Date(2026, 3, 13, 0, 0, 0, 0, time.Local)))
+}
+
+func TestNewRestrictionsValidation(t *testing.T) {
+	_, err := NewRestrictions(&config.QuietHoursConfig{Start: "22:00", End: "22:00"}, nil)
+	assert.Error(t, err)
+
+	_, err = NewRestrictions(&config.QuietHoursConfig{Start: "10pm", End: "07:00"}, nil)
+	assert.Error(t, err)
+
+	_, err = NewRestrictions(nil, []string{"24.12."})
+	assert.Error(t, err)
+
+	r, err := NewRestrictions(nil, nil)
+	require.NoError(t, err)
+	now := time.Now()
+	assert.Equal(t, now, r.NextAllowed(now))
+}
+
+func TestScheduler(t *testing.T) {
+	var executed atomic.Int32
+	s, err := New("", func(Delivery) { executed.Add(1) })
+	require.NoError(t, err)
+
+	s.Schedule(Delivery{At: time.Now()})
+	s.Schedule(Delivery{At: time.Now().Add(50 * time.Millisecond)})
+	s.Schedule(Delivery{At: time.Now().Add(time.Hour)})
+
+	assert.Eventually(t, func() bool { return executed.Load() == 2 }, time.Second, 10*time.Millisecond)
+	assert.Never(t, func() bool { return executed.Load() > 2 }, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestSchedulerPersistence(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "deliveries.json")
+
+	var delivered atomic.Int32
+	s, err := New(stateFile, func(Delivery) { delivered.Add(1) })
+	require.NoError(t, err)
+
+	pending := Delivery{
+		At:         time.Now().Add(time.Hour).Truncate(time.Second),
+		Contact:    "uid:joe",
+		Kind:       KindNotification,
+		Notifier:   "slack",
+		Occurrence: time.Date(2026, 3, 13, 0, 0, 0, 0, time.Local),
+	}
+	s.Schedule(pending)
+	s.Schedule(Delivery{At: time.Now(), Contact: "uid:jane", Kind: KindGreeting})
+
+	// Delivered entries are removed from the state
+	require.Eventually(t, func() bool { return delivered.Load() == 1 }, time.Second, 10*time.Millisecond)
+
+	// After a restart the pending delivery is loaded but not delivered
+	// before resuming
+	var restored []Delivery
+	s, err = New(stateFile, func(d Delivery) { restored = append(restored, d) })
+	require.NoError(t, err)
+	require.Len(t, s.pending, 1)
+	assert.True(t, pending.At.Equal(s.pending[0].At))
+	assert.Equal(t, pending.Notifier, s.pending[0].Notifier)
+	assert.True(t, pending.Occurrence.Equal(s.pending[0].Occurrence))
+	assert.Empty(t, restored)
+
+	// Missing and corrupt state files
+	_, err = New(filepath.Join(t.TempDir(), "missing.json"), func(Delivery) {})
+	assert.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(stateFile, []byte("nope"), 0o600))
+	_, err = New(stateFile, func(Delivery) {})
+	assert.Error(t, err)
+}
+
+func TestSchedulerResume(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "deliveries.json")
+
+	s, err := New(stateFile, func(Delivery) {})
+	require.NoError(t, err)
+	s.lock.Lock()
+	s.pending[0] = Delivery{At: time.Now().Add(-time.Hour), Contact: "uid:joe", Kind: KindNotification}
+	require.NoError(t, s.save())
+	s.lock.Unlock()
+
+	// Deliveries missed while not running are delivered immediately
+	var delivered atomic.Int32
+	s, err = New(stateFile, func(Delivery) { delivered.Add(1) })
+	require.NoError(t, err)
+	s.Resume()
+
+	assert.Eventually(t, func() bool { return delivered.Load() == 1 }, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,174 @@
+// Package secrets contains the resolution of secrets referenced in the
+// configuration (environment variables, files and commands) and their
+// redaction from log output and error messages
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	commandTimeout = 10 * time.Second
+	// minRedactLength prevents short values (i.e. `1`) from being
+	// replaced all over the log output
+	minRedactLength = 4
+	redacted        = "[redacted]"
+)
+
+var (
+	envPattern = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+	// sensitiveKeyParts mark keys in the configuration whose values
+	// are secrets (compared case-insensitive)
+	sensitiveKeyParts = []string{"authorization", "key", "pass", "secret", "token", "webhook"}
+
+	registry = struct {
+		lock     sync.RWMutex
+		replacer *strings.Replacer
+		values   []string
+	}{replacer: strings.NewReplacer()}
+)
+
+type (
+	// RedactingFormatter wraps a logrus.Formatter and replaces all
+	// registered secrets in its output
+	RedactingFormatter struct {
+		Formatter logrus.Formatter
+	}
+)
+
+// ExpandEnv replaces `${VAR}` references with the value of the
+// environment variable and `$${` with a literal `${`. It returns
+// whether any variable was replaced. References to unset variables are
+// kept as they are (i.e. to be expanded by a shell later) unless strict
+// is set which makes them fail.
+func ExpandEnv(s string, strict bool) (_ string, expanded bool, err error) {
+	out := envPattern.ReplaceAllStringFunc(s, func(match string) string {
+		if match == "$${" {
+			return "${"
+		}
+
+		name := envPattern.FindStringSubmatch(match)[1]
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			if strict && err == nil {
+				err = fmt.Errorf("environment variable %q is not set", name)
+			}
+			return match
+		}
+
+		expanded = true
+		return value
+	})
+
+	return out, expanded, err
+}
+
+// FromCommand executes the command through the shell and returns its
+// output without trailing newlines, i.e. to read the password from a
+// password manager. The output is registered as secret.
+func FromCommand(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	stdout := new(bytes.Buffer)
+
+	cmd := osexec.CommandContext(ctx, "/bin/sh", "-c", command) //#nosec:G204 // Intended to run configured command
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+
+	// The output of the command is not included as it might contain
+	// parts of the secret
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("running command: %w", err)
+	}
+
+	value := strings.TrimRight(stdout.String(), "\r\n")
+	Register(value)
+
+	return value, nil
+}
+
+// FromFile reads the file and returns its content without trailing
+// newlines, i.e. to read Docker / systemd secrets. The content is
+// registered as secret.
+func FromFile(path string) (string, error) {
+	raw, err := os.ReadFile(path) //#nosec:G304 // Intended to read configured file
+	if err != nil {
+		return "", fmt.Errorf("reading file: %w", err)
+	}
+
+	value := strings.TrimRight(string(raw), "\r\n")
+	Register(value)
+
+	return value, nil
+}
+
+// IsSensitiveKey reports whether the key of a setting (i.e. `pass`,
+// `apiToken` or `webhook`) indicates its value is a secret. Keys
+// referencing the source of a secret (`passFile`, `passCommand`) are
+// not sensitive themselves.
+func IsSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+
+	if strings.HasSuffix(key, "file") || strings.HasSuffix(key, "command") {
+		return false
+	}
+
+	return slices.ContainsFunc(sensitiveKeyParts, func(part string) bool {
+		return strings.Contains(key, part)
+	})
+}
+
+// Redact replaces all registered secrets within the string
+func Redact(s string) string {
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+
+	return registry.replacer.Replace(s)
+}
+
+// Register adds values to be redacted from log output. Values shorter
+// than a few characters are ignored.
+func Register(values ...string) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+
+	for _, v := range values {
+		if len(v) < minRedactLength || slices.Contains(registry.values, v) {
+			continue
+		}
+		registry.values = append(registry.values, v)
+	}
+
+	// Longer secrets first to fully redact secrets containing others
+	slices.SortFunc(registry.values, func(a, b string) int { return len(b) - len(a) })
+
+	var pairs []string
+	for _, v := range registry.values {
+		pairs = append(pairs, v, redacted)
+	}
+
+	registry.replacer = strings.NewReplacer(pairs...)
+}
+
+// Format implements the logrus.Formatter interface
+func (r RedactingFormatter) Format(e *logrus.Entry) ([]byte, error) {
+	out, err := r.Formatter.Format(e)
+	if err != nil {
+		return nil, fmt.Errorf("formatting entry: %w", err)
+	}
+
+	return []byte(Redact(string(out))), nil
+}
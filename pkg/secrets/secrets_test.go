@@ -0,0 +1,77 @@
+package secrets
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("BN_TEST_TOKEN", "s3cr3t")
+
+	v, expanded, err := ExpandEnv("token=${BN_TEST_TOKEN}", true)
+	require.NoError(t, err)
+	assert.True(t, expanded)
+	assert.Equal(t, "token=s3cr3t", v)
+
+	v, expanded, err = ExpandEnv("no $BN_TEST_TOKEN or $${BN_TEST_TOKEN} expansion", true)
+	require.NoError(t, err)
+	assert.False(t, expanded)
+	assert.Equal(t, "no $BN_TEST_TOKEN or ${BN_TEST_TOKEN} expansion", v)
+
+	v, expanded, err = ExpandEnv("echo ${BN_TEST_UNSET} ${BN_TEST_TOKEN}", false)
+	require.NoError(t, err)
+	assert.True(t, expanded)
+	assert.Equal(t, "echo ${BN_TEST_UNSET} s3cr3t", v)
+
+	_, _, err = ExpandEnv("${BN_TEST_UNSET}", true)
+	assert.ErrorContains(t, err, "BN_TEST_UNSET")
+}
+
+func TestFromFileAndCommand(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(file, []byte("file-secret\n"), 0o600))
+
+	v, err := FromFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "file-secret", v)
+	assert.Equal(t, "pass=[redacted]", Redact("pass=file-secret"))
+
+	v, err = FromCommand("echo command-secret")
+	require.NoError(t, err)
+	assert.Equal(t, "command-secret", v)
+	assert.Equal(t, "pass=[redacted]", Redact("pass=command-secret"))
+
+	_, err = FromCommand("exit 1")
+	assert.Error(t, err)
+
+	_, err = FromFile(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
+
+func TestIsSensitiveKey(t *testing.T) {
+	for _, key := range []string{"pass", "apiToken", "botToken", "secret", "userKey", "webhook", "Authorization"} {
+		assert.True(t, IsSensitiveKey(key), key)
+	}
+
+	for _, key := range []string{"passFile", "passCommand", "user", "baseURL", "channel"} {
+		assert.False(t, IsSensitiveKey(key), key)
+	}
+}
+
+func TestRedactingFormatter(t *testing.T) {
+	Register("abc", "topsecret", "topsecret-but-longer")
+
+	buf := new(bytes.Buffer)
+	logger := logrus.New()
+	logger.SetOutput(buf)
+	logger.SetFormatter(RedactingFormatter{Formatter: &logrus.TextFormatter{DisableTimestamp: true}})
+
+	logger.WithField("url", "https://example.com/topsecret-but-longer").Error("failed with topsecret for abc")
+	assert.Equal(t, "level=error msg=\"failed with [redacted] for abc\" url=\"https://example.com/[redacted]\"\n", buf.String())
+}
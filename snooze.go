@@ -0,0 +1,109 @@
+package main
+
+import (
+	"slices"
+	"time"
+
+	"github.com/emersion/go-vcard"
+	"github.com/sirupsen/logrus"
+
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/actions"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/config"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/notifier"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/routing"
+	"git.luzifer.io/luzifer/birthday-notifier/pkg/scheduler"
+)
+
+// notifierEvent adapts the event to the notifier: Sets its locale, the
+// template configured for the event and the links to the actions
+func notifierEvent(notifierCfg config.NotifierConfig, actionManager *actions.Manager, evt notifier.Event) notifier.Event {
+	evt.Locale = notifierCfg.Locale
+	evt.Template = notifierTemplate(notifierCfg, evt)
+	evt.Actions = actionManager.URLs(actions.ContactKey(evt.Contact), evt.Date, notifierCfg.Name)
+	return evt
+}
+
+// scheduleEscalation registers the follow-up notifications of the
+// escalation steps of the target: They are sent relative to the
+// initial notification (taking deferrals by quiet hours into account)
+// unless the notification is acknowledged before
+func scheduleEscalation(
+	configFile config.File,
+	actionManager *actions.Manager,
+	restrictions []scheduler.Restrictions,
+	target routing.Target,
+	contact vcard.Card,
+	occurrence time.Time,
+) {
+	if len(target.Escalation) == 0 || len(target.Notifiers) == 0 {
+		return
+	}
+
+	var sentAt time.Time
+	for _, i := range target.Notifiers {
+		if at := restrictions[i].NextAllowed(time.Now()); sentAt.IsZero() || at.Before(sentAt) {
+			sentAt = at
+		}
+	}
+
+	contactKey := actions.ContactKey(contact)
+
+	for _, step := range target.Escalation {
+		for _, i := range step.Notifiers {
+			if err := actionManager.Escalate(actions.Snooze{
+				Contact:    contactKey,
+				Notifier:   configFile.Notifiers[i].Name,
+				Occurrence: occurrence,
+				Until:      sentAt.Add(step.After),
+			}); err != nil {
+				logrus.
+					WithError(err).
+					WithFields(logrus.Fields{
+						"name":     contact.PreferredValue(vcard.FieldFormattedName),
+						"notifier": configFile.Notifiers[i].Name,
+					}).
+					Error("scheduling escalation")
+			}
+		}
+	}
+}
+
+// redeliverSnoozed creates the function scheduling the delivery of
+// snoozed and escalated notifications: When the snooze is over the
+// notification is scheduled (again) through the notifier unless the
+// birthday has been acknowledged in the meantime, the notifier vanished
+// or has been disabled
+func redeliverSnoozed(
+	configFile config.File,
+	sched *scheduler.Scheduler,
+	restrictions []scheduler.Restrictions,
+) func(*actions.Manager, actions.Snooze) {
+	return func(actionManager *actions.Manager, s actions.Snooze) {
+		// Snoozes are persisted by the actions, they must not be stored
+		// in the scheduler before they are taken from the actions
+		time.AfterFunc(time.Until(s.Until), func() {
+			logger := logrus.WithFields(logrus.Fields{
+				"contact":  s.Contact,
+				"notifier": s.Notifier,
+			})
+
+			if !actionManager.Take(s) {
+				logger.Debug("follow-up acknowledged or replaced, skipping")
+				return
+			}
+
+			idx := slices.IndexFunc(configFile.Notifiers, func(n config.NotifierConfig) bool { return n.Name == s.Notifier })
+			if idx < 0 {
+				logger.Warn("notifier of follow-up notification not found")
+				return
+			}
+
+			if !configFile.Notifiers[idx].IsEnabled() {
+				logger.Info("notifier of follow-up notification is disabled, skipping")
+				return
+			}
+
+			scheduleNotification(sched, restrictions[idx], s.Notifier, s.Contact, s.Occurrence)
+		})
+	}
+}